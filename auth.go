@@ -0,0 +1,102 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Subject identifies the caller, such as a user or service account id.
+	Subject string
+
+	// Scopes are the scopes granted to the caller.
+	Scopes []string
+
+	// Claims holds additional attributes of the caller.
+	Claims map[string]interface{}
+}
+
+// Authenticator is the interface used for servers authenticating requests
+// before methods are invoked, returning the principal making the request.
+type Authenticator interface {
+	Authenticate(ctx context.Context, r *http.Request) (*Principal, error)
+}
+
+// principalKey is a private context key for the principal.
+type principalKey struct{}
+
+// Authenticate returns ctx with the principal of r authenticated by s when
+// it implements Authenticator. Errors which do not provide a status code, or
+// a nil principal, are returned as Unauthorized errors.
+func Authenticate(ctx context.Context, s interface{}, r *http.Request) (context.Context, error) {
+	a, ok := s.(Authenticator)
+	if !ok {
+		return ctx, nil
+	}
+
+	p, err := a.Authenticate(ctx, r)
+	if err != nil {
+		if e := StatusProvider(nil); errors.As(err, &e) {
+			return nil, err
+		}
+		return nil, Unauthorized("Unauthorized")
+	}
+
+	if p == nil {
+		return nil, Unauthorized("Unauthorized")
+	}
+
+	return NewPrincipalContext(ctx, p), nil
+}
+
+// HasScope returns true if the principal was granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize returns an Unauthorized error when ctx has no principal, or a
+// Forbidden error when the principal was not granted each of the scopes.
+func Authorize(ctx context.Context, scopes ...string) error {
+	p := PrincipalFromContext(ctx)
+	if p == nil {
+		return Unauthorized("Unauthorized")
+	}
+
+	for _, scope := range scopes {
+		if !p.HasScope(scope) {
+			return Forbidden(fmt.Sprintf("Missing scope %q", scope))
+		}
+	}
+
+	return nil
+}
+
+// NewPrincipalContext returns a new context with the principal p.
+func NewPrincipalContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal from context, or nil for
+// unauthenticated requests.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// BearerToken returns the token of the Bearer Authorization header of r, or an empty string.
+func BearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(h[7:])
+}
@@ -0,0 +1,103 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// authServer is a server authenticating the token "secret".
+type authServer struct{}
+
+// Authenticate implementation.
+func (authServer) Authenticate(ctx context.Context, r *http.Request) (*rpc.Principal, error) {
+	switch rpc.BearerToken(r) {
+	case "secret":
+		return &rpc.Principal{Subject: "tobi"}, nil
+	case "banned":
+		return nil, rpc.Error(http.StatusForbidden, "banned", "Account suspended")
+	default:
+		return nil, errors.New("invalid token")
+	}
+}
+
+// Test authentication.
+func TestAuthenticate(t *testing.T) {
+	t.Run("with a valid token", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		ctx, err := rpc.Authenticate(context.Background(), authServer{}, r)
+		assert.NoError(t, err)
+		assert.Equal(t, "tobi", rpc.PrincipalFromContext(ctx).Subject)
+	})
+
+	t.Run("with an invalid token", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Authorization", "Bearer nope")
+		_, err := rpc.Authenticate(context.Background(), authServer{}, r)
+		assert.EqualError(t, err, "Unauthorized")
+		assert.Equal(t, http.StatusUnauthorized, err.(rpc.StatusProvider).StatusCode())
+		assert.Equal(t, "unauthorized", err.(rpc.TypeProvider).Type())
+	})
+
+	t.Run("with an error providing a status", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Authorization", "Bearer banned")
+		_, err := rpc.Authenticate(context.Background(), authServer{}, r)
+		assert.Equal(t, "banned", err.(rpc.TypeProvider).Type())
+	})
+
+	t.Run("without an authenticator", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		ctx, err := rpc.Authenticate(context.Background(), struct{}{}, r)
+		assert.NoError(t, err)
+		assert.Nil(t, rpc.PrincipalFromContext(ctx))
+	})
+}
+
+// Test bearer tokens.
+func TestBearerToken(t *testing.T) {
+	cases := map[string]string{
+		"Bearer secret":  "secret",
+		"bearer secret":  "secret",
+		"Basic c2VjcmV0": "",
+		"Bearer":         "",
+		"":               "",
+	}
+
+	for header, token := range cases {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Authorization", header)
+		assert.Equal(t, token, rpc.BearerToken(r), header)
+	}
+}
+
+// Test authorization.
+func TestAuthorize(t *testing.T) {
+	ctx := rpc.NewPrincipalContext(context.Background(), &rpc.Principal{
+		Subject: "tobi",
+		Scopes:  []string{"items:read", "items:write"},
+	})
+
+	t.Run("with the scopes granted", func(t *testing.T) {
+		assert.NoError(t, rpc.Authorize(ctx, "items:read", "items:write"))
+	})
+
+	t.Run("with a scope missing", func(t *testing.T) {
+		err := rpc.Authorize(ctx, "items:write", "users:write")
+		assert.EqualError(t, err, `Missing scope "users:write"`)
+		assert.Equal(t, http.StatusForbidden, err.(rpc.StatusProvider).StatusCode())
+		assert.Equal(t, "forbidden", err.(rpc.TypeProvider).Type())
+	})
+
+	t.Run("without a principal", func(t *testing.T) {
+		err := rpc.Authorize(context.Background(), "items:write")
+		assert.Equal(t, "unauthorized", err.(rpc.TypeProvider).Type())
+	})
+}
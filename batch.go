@@ -0,0 +1,147 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// BatchConcurrency is the maximum number of calls of a batch run concurrently.
+var BatchConcurrency = 8
+
+// MaxBatchSize is the maximum number of calls in a batch.
+var MaxBatchSize = 100
+
+// Caller is the function used for invoking the method at path with the input read from r.
+// Callers of ServeBatch authenticate r like a single call of the method before invoking it.
+type Caller func(ctx context.Context, path string, r *http.Request) (interface{}, error)
+
+// batchCall is a call in a batch request.
+type batchCall struct {
+	Method string     `json:"method"`
+	Input  batchInput `json:"input"`
+}
+
+// batchResult is the result of a call in a batch response, where
+// successful calls without a result have a null result.
+type batchResult struct {
+	Status int                  `json:"status"`
+	Result *interface{}         `json:"result,omitempty"`
+	Error  *serverErrorResponse `json:"error,omitempty"`
+}
+
+// batchInput is the input of a batch call, kept in the encoding of the request.
+type batchInput []byte
+
+// UnmarshalJSON implementation.
+func (b *batchInput) UnmarshalJSON(data []byte) error {
+	if string(data) != "null" {
+		*b = append((*b)[:0], data...)
+	}
+	return nil
+}
+
+// DecodeMsgpack implementation.
+func (b *batchInput) DecodeMsgpack(dec *msgpack.Decoder) error {
+	raw, err := dec.DecodeRaw()
+	*b = batchInput(raw)
+	return err
+}
+
+// UnmarshalCBOR implementation.
+func (b *batchInput) UnmarshalCBOR(data []byte) error {
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// ServeBatch reads an array of calls, each with a method name and input, and
+// invokes them using call with at most BatchConcurrency running at a time.
+// The response holds the status code and result of each call in order, or
+// its error in the format written by WriteError.
+//
+// Each call receives a copy of r with the method's path and input as its body,
+// so limits, validation and interceptors apply as they do to single calls.
+// Routers must not authenticate the batch request itself, but each of its
+// calls as they would a single call, see Caller.
+// Batch inputs are supported by the built-in codecs.
+func ServeBatch(w http.ResponseWriter, r *http.Request, call Caller) {
+	var calls []batchCall
+	err := ReadRequest(r, &calls)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if len(calls) > MaxBatchSize {
+		WriteError(w, BadRequest(fmt.Sprintf("Batch must not exceed %d calls", MaxBatchSize)))
+		return
+	}
+
+	// ensure the calls share the request id before they run concurrently
+	RequestID(r)
+
+	c, _ := LookupCodec(r.Header.Get("Content-Type"))
+	results := make([]batchResult, len(calls))
+	sem := make(chan struct{}, BatchConcurrency)
+	var wg sync.WaitGroup
+
+	for i, bc := range calls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, bc batchCall) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = invoke(r, c, bc, call)
+		}(i, bc)
+	}
+
+	wg.Wait()
+	WriteResponse(w, results)
+}
+
+// invoke invokes the batch call bc of r using call.
+func invoke(r *http.Request, c Codec, bc batchCall, call Caller) batchResult {
+	in := []byte(bc.Input)
+
+	// missing inputs default to an empty object
+	if len(in) == 0 {
+		var buf bytes.Buffer
+		if err := c.Encode(&buf, struct{}{}); err != nil {
+			return failed(err, RequestID(r))
+		}
+		in = buf.Bytes()
+	}
+
+	req := r.Clone(r.Context())
+	req.URL.Path = "/" + bc.Method
+	req.Header.Del("Content-Encoding")
+	req.Header.Del("Content-Length")
+	req.Body = ioutil.NopCloser(bytes.NewReader(in))
+	req.ContentLength = int64(len(in))
+
+	res, err := call(NewRequestContext(req.Context(), req), req.URL.Path, req)
+	if err != nil {
+		return failed(err, RequestID(req))
+	}
+
+	return batchResult{
+		Status: http.StatusOK,
+		Result: &res,
+	}
+}
+
+// failed returns the batch result for err of the request with the given id.
+func failed(err error, requestID string) batchResult {
+	status, body := errorResponse(err, requestID)
+	return batchResult{
+		Status: status,
+		Error:  &body,
+	}
+}
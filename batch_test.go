@@ -0,0 +1,134 @@
+package rpc_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// echo is a Caller echoing the input of the echo method.
+func echo(ctx context.Context, path string, r *http.Request) (interface{}, error) {
+	switch path {
+	case "/echo":
+		var in map[string]interface{}
+		err := rpc.ReadRequest(r, &in)
+		return in, err
+	default:
+		return nil, rpc.BadRequest("Invalid method")
+	}
+}
+
+// Test batches.
+func TestServeBatch(t *testing.T) {
+	t.Run("with calls", func(t *testing.T) {
+		body := `[{"method":"echo","input":{"n":1}},{"method":"nope"},{"method":"echo"}]`
+		r := httptest.NewRequest("POST", "/_batch", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-Request-Id", "abc")
+		w := httptest.NewRecorder()
+		rpc.ServeBatch(w, r, echo)
+		assert.Equal(t, 200, w.Code)
+		assert.JSONEq(t, `[
+			{"status":200,"result":{"n":1}},
+			{"status":400,"error":{"type":"bad_request","message":"Invalid method","request_id":"abc"}},
+			{"status":200,"result":{}}
+		]`, w.Body.String())
+	})
+
+	t.Run("with calls without results", func(t *testing.T) {
+		call := func(ctx context.Context, path string, r *http.Request) (interface{}, error) {
+			return nil, nil
+		}
+
+		for _, c := range []rpc.Codec{rpc.JSON, rpc.MessagePack, rpc.CBOR} {
+			var body bytes.Buffer
+			c.Encode(&body, []map[string]interface{}{{"method": "a"}})
+			r := httptest.NewRequest("POST", "/_batch", &body)
+			r.Header.Set("Content-Type", c.ContentType())
+			r.Header.Set("Accept", c.ContentType())
+			w := httptest.NewRecorder()
+			rpc.ServeBatch(rpc.Negotiate(w, r), r, call)
+			assert.Equal(t, 200, w.Code)
+
+			var res []map[string]interface{}
+			assert.NoError(t, c.Decode(w.Body, &res), c.Name())
+			assert.Len(t, res, 1)
+			result, ok := res[0]["result"]
+			assert.True(t, ok, c.Name())
+			assert.Nil(t, result, c.Name())
+		}
+	})
+
+	t.Run("with messagepack", func(t *testing.T) {
+		var body bytes.Buffer
+		rpc.MessagePack.Encode(&body, []map[string]interface{}{{"method": "echo", "input": map[string]int{"n": 1}}})
+		r := httptest.NewRequest("POST", "/_batch", &body)
+		r.Header.Set("Content-Type", "application/msgpack")
+		w := httptest.NewRecorder()
+		rpc.ServeBatch(rpc.Negotiate(w, r), r, echo)
+		assert.Equal(t, 200, w.Code)
+
+		var res []struct {
+			Status int            `json:"status"`
+			Result map[string]int `json:"result"`
+		}
+		assert.NoError(t, rpc.MessagePack.Decode(w.Body, &res))
+		assert.Equal(t, 200, res[0].Status)
+		assert.Equal(t, map[string]int{"n": 1}, res[0].Result)
+	})
+
+	t.Run("with a malformed batch", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/_batch", strings.NewReader(`{"method":"echo"}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		rpc.ServeBatch(w, r, echo)
+		assert.Equal(t, 400, w.Code)
+	})
+
+	t.Run("with too many calls", func(t *testing.T) {
+		defer func(n int) { rpc.MaxBatchSize = n }(rpc.MaxBatchSize)
+		rpc.MaxBatchSize = 1
+		r := httptest.NewRequest("POST", "/_batch", strings.NewReader(`[{"method":"echo"},{"method":"echo"}]`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		rpc.ServeBatch(w, r, echo)
+		assert.Equal(t, 400, w.Code)
+		assert.JSONEq(t, `{"type":"bad_request","message":"Batch must not exceed 1 calls"}`, w.Body.String())
+	})
+
+	t.Run("with bounded concurrency", func(t *testing.T) {
+		defer func(n int) { rpc.BatchConcurrency = n }(rpc.BatchConcurrency)
+		rpc.BatchConcurrency = 2
+
+		var running, max int32
+		call := func(ctx context.Context, path string, r *http.Request) (interface{}, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			return nil, nil
+		}
+
+		body := `[{"method":"a"},{"method":"b"},{"method":"c"},{"method":"d"},{"method":"e"}]`
+		r := httptest.NewRequest("POST", "/_batch", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		rpc.ServeBatch(w, r, call)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, int32(2), max)
+	})
+}
@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// DefaultCacheControl is the Cache-Control header of cacheable responses
+// which do not specify one, requiring clients to revalidate them.
+const DefaultCacheControl = "no-cache"
+
+// WriteCacheable writes a response like WriteResponse, with a weak ETag of
+// its encoded body and the given Cache-Control header, defaulting to
+// DefaultCacheControl. When the If-None-Match header of r matches the
+// ETag a 304 is written without the body.
+func WriteCacheable(w http.ResponseWriter, r *http.Request, value interface{}, cacheControl string) {
+	if value == nil {
+		WriteResponse(w, value)
+		return
+	}
+
+	if cacheControl == "" {
+		cacheControl = DefaultCacheControl
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	c, err := encode(w, buf, value)
+	if err != nil {
+		status, c := encodeError(w, buf, err)
+		writeBody(w, status, c.ContentType(), buf.Bytes())
+		return
+	}
+
+	tag := etag(c.ContentType(), buf.Bytes())
+	w.Header().Set("ETag", tag)
+	w.Header().Set("Cache-Control", cacheControl)
+
+	if etagMatch(r.Header.Get("If-None-Match"), tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeBody(w, http.StatusOK, c.ContentType(), buf.Bytes())
+}
+
+// etag returns the weak entity tag of the body b with the given content type.
+func etag(contentType string, b []byte) string {
+	h := sha256.New()
+	h.Write([]byte(contentType))
+	h.Write([]byte{0})
+	h.Write(b)
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagMatch returns true if the If-None-Match header value matches tag,
+// using the weak comparison.
+func etagMatch(header, tag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package rpc_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// Test cacheable responses.
+func TestWriteCacheable(t *testing.T) {
+	out := struct {
+		Items []string `json:"items"`
+	}{[]string{"Ferret food"}}
+
+	get := func(etag, cacheControl string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/get_items", nil)
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		rpc.WriteCacheable(rpc.Negotiate(w, r), r, out, cacheControl)
+		return w
+	}
+
+	t.Run("without a cached response", func(t *testing.T) {
+		w := get("", "")
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "{\"items\":[\"Ferret food\"]}\n", w.Body.String())
+		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+		assert.Regexp(t, `^W/"[0-9a-f]{32}"$`, w.Header().Get("ETag"))
+	})
+
+	t.Run("with a value which fails to encode", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/get_items", nil)
+		w := httptest.NewRecorder()
+		rpc.WriteCacheable(rpc.Negotiate(w, r), r, map[string]interface{}{"items": make(chan int)}, "")
+		assert.Equal(t, 500, w.Code)
+		assert.Empty(t, w.Header().Get("ETag"))
+		assert.Contains(t, w.Body.String(), `"type":"internal"`)
+	})
+
+	t.Run("with a matching etag", func(t *testing.T) {
+		etag := get("", "").Header().Get("ETag")
+		w := get(`"other", `+etag, "max-age=60")
+		assert.Equal(t, 304, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+		assert.Equal(t, "max-age=60", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("with a strong etag", func(t *testing.T) {
+		etag := get("", "").Header().Get("ETag")
+		w := get(etag[2:], "")
+		assert.Equal(t, 304, w.Code)
+	})
+
+	t.Run("with a wildcard", func(t *testing.T) {
+		w := get("*", "")
+		assert.Equal(t, 304, w.Code)
+	})
+
+	t.Run("with a stale etag", func(t *testing.T) {
+		w := get(`W/"other"`, "")
+		assert.Equal(t, 200, w.Code)
+		assert.NotEmpty(t, w.Body.String())
+	})
+
+	t.Run("with no content", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/get_items", nil)
+		w := httptest.NewRecorder()
+		rpc.WriteCacheable(w, r, nil, "")
+		assert.Equal(t, 204, w.Code)
+		assert.Empty(t, w.Header().Get("ETag"))
+	})
+}
@@ -39,11 +39,19 @@ func generate(w io.Writer, s *schema.Schema, pkg string) error {
 	out(w, "package %s\n\n", pkg)
 
 	out(w, "import (\n")
+	out(w, "  \"bufio\"\n")
 	out(w, "  \"bytes\"\n")
+	out(w, "  \"compress/gzip\"\n")
+	out(w, "  \"context\"\n")
+	out(w, "  \"crypto/rand\"\n")
+	out(w, "  \"encoding/hex\"\n")
 	out(w, "  \"encoding/json\"\n")
+	out(w, "  \"errors\"\n")
 	out(w, "  \"fmt\"\n")
 	out(w, "  \"io\"\n")
 	out(w, "  \"net/http\"\n")
+	out(w, "  \"net/url\"\n")
+	out(w, "  \"strconv\"\n")
 	out(w, "  \"time\"\n")
 	out(w, ")\n\n")
 
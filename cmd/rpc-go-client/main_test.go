@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc/schema"
+)
+
+// streamTest tests the generated client against streams with and without their end frame.
+const streamTest = `package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func watch(t *testing.T, body string) ([]string, error) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		io.WriteString(w, body)
+	}))
+	defer ts.Close()
+
+	c := &Client{URL: ts.URL}
+	s, err := c.WatchItems(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var items []string
+	for s.Next() {
+		items = append(items, s.Value().Item.Text)
+	}
+	return items, s.Err()
+}
+
+func TestStream(t *testing.T) {
+	items, err := watch(t, "{\"data\":{\"item\":{\"text\":\"a\"}}}\n{\"end\":true}\n")
+	if err != nil || len(items) != 1 {
+		t.Fatal(items, err)
+	}
+
+	items, err = watch(t, "{\"data\":{\"item\":{\"text\":\"a\"}}}\n")
+	if err != io.ErrUnexpectedEOF || len(items) != 1 {
+		t.Fatal(items, err)
+	}
+
+	items, err = watch(t, "{\"data\":{\"item\":{\"text\":\"a\"}}}\n{\"data\":{\"it")
+	if err != io.ErrUnexpectedEOF || len(items) != 1 {
+		t.Fatal(items, err)
+	}
+}
+`
+
+// Test the generated client.
+func TestGenerate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("building the generated client")
+	}
+
+	s, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	var buf bytes.Buffer
+	err = generate(&buf, s, "client")
+	assert.NoError(t, err, "generating")
+
+	dir, err := ioutil.TempDir("", "client")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"go.mod":         "module client\n\ngo 1.13\n",
+		"client.go":      buf.String(),
+		"client_test.go": streamTest,
+	}
+
+	for name, content := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		assert.NoError(t, err)
+	}
+
+	cmd := exec.Command("go", "test", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	out, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
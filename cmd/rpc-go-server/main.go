@@ -17,6 +17,7 @@ func main() {
 	pkg := flag.String("package", "server", "Name of the package")
 	types := flag.String("types", "", "Types package to import")
 	logging := flag.Bool("logging", true, "Enable logging generation")
+	batch := flag.Bool("batch", false, "Enable the /_batch route")
 	flag.Parse()
 
 	s, err := schema.Load(*path)
@@ -24,14 +25,14 @@ func main() {
 		log.Fatalf("error: %s", err)
 	}
 
-	err = generate(os.Stdout, s, *pkg, *types, *logging)
+	err = generate(os.Stdout, s, *pkg, *types, *logging, *batch)
 	if err != nil {
 		log.Fatalf("error: %s", err)
 	}
 }
 
 // generate implementation.
-func generate(w io.Writer, s *schema.Schema, pkg, types string, logging bool) error {
+func generate(w io.Writer, s *schema.Schema, pkg, types string, logging, batch bool) error {
 	out := fmt.Fprintf
 
 	// TODO: move these to generator
@@ -43,6 +44,7 @@ func generate(w io.Writer, s *schema.Schema, pkg, types string, logging bool) er
 	out(w, "  \"net/http\"\n")
 	out(w, "\n")
 	out(w, "  \"github.com/apex/rpc\"\n")
+	out(w, "  \"github.com/apex/rpc/metrics\"\n")
 	out(w, "  \"github.com/apex/log\"\n")
 	if len(types) > 0 {
 		out(w, "\n")
@@ -53,7 +55,7 @@ func generate(w io.Writer, s *schema.Schema, pkg, types string, logging bool) er
 	if len(types) > 0 {
 		types = path.Base(types)
 	}
-	err := goserver.Generate(w, s, logging, types)
+	err := goserver.Generate(w, s, logging, types, batch)
 	if err != nil {
 		return fmt.Errorf("generating client: %w", err)
 	}
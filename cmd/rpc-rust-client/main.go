@@ -21,6 +21,7 @@ import (
 // serde_derive = "1.0"
 // bytes = "0.5"
 // chrono = { version = "0.4", features = ["serde"] }
+// flate2 = "1.0"
 // reqwest = { version = "0.10", features = ["json"] }
 // tokio = { version = "0.2", features = ["full"] }
 //
@@ -0,0 +1,260 @@
+package rpc
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec is the interface used for encoding and decoding request and response bodies.
+type Codec interface {
+	// Name returns a human-friendly name used in error messages.
+	Name() string
+
+	// ContentType returns the media type handled by the codec.
+	ContentType() string
+
+	// Encode writes the encoding of v to w.
+	Encode(w io.Writer, v interface{}) error
+
+	// Decode reads the next encoded value from r and stores it in v.
+	Decode(r io.Reader, v interface{}) error
+}
+
+// Codecs available by default.
+var (
+	JSON        Codec = jsonCodec{}
+	MessagePack Codec = msgpackCodec{}
+	CBOR        Codec = cborCodec{}
+)
+
+// codecs is the registry of codecs by media type.
+var codecs = map[string]Codec{
+	JSON.ContentType():        JSON,
+	MessagePack.ContentType(): MessagePack,
+	CBOR.ContentType():        CBOR,
+}
+
+// RegisterCodec registers c for its media type, replacing any existing codec.
+// It is not safe for concurrent use and should be called during initialization.
+func RegisterCodec(c Codec) {
+	codecs[c.ContentType()] = c
+}
+
+// LookupCodec returns the codec registered for the given Content-Type header value.
+func LookupCodec(contentType string) (Codec, bool) {
+	// avoid parsing media types without parameters
+	if c, ok := codecs[contentType]; ok {
+		return c, true
+	}
+
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	c, ok := codecs[t]
+	return c, ok
+}
+
+// NegotiateCodec returns the codec preferred by the Accept header of r. When
+// no acceptable codec is registered the codec of the request body is used,
+// defaulting to JSON.
+func NegotiateCodec(r *http.Request) Codec {
+	for _, t := range acceptedTypes(r.Header.Get("Accept")) {
+		if c, ok := codecs[t]; ok {
+			return c
+		}
+	}
+
+	if c, ok := LookupCodec(r.Header.Get("Content-Type")); ok {
+		return c
+	}
+
+	return JSON
+}
+
+// supportedTypes returns a formatted list of the registered media types.
+func supportedTypes() string {
+	var types []string
+	for t := range codecs {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	switch len(types) {
+	case 1:
+		return types[0]
+	default:
+		return strings.Join(types[:len(types)-1], ", ") + " or " + types[len(types)-1]
+	}
+}
+
+// acceptedTypes returns the values of an Accept or Accept-Encoding header
+// ordered by preference.
+func acceptedTypes(accept string) (types []string) {
+	var weights []float64
+
+	for _, part := range strings.Split(accept, ",") {
+		t, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		if q <= 0 {
+			continue
+		}
+
+		types = append(types, t)
+		weights = append(weights, q)
+	}
+
+	sort.Stable(byWeight{types, weights})
+	return
+}
+
+// byWeight sorts header values by descending quality.
+type byWeight struct {
+	types   []string
+	weights []float64
+}
+
+func (b byWeight) Len() int           { return len(b.types) }
+func (b byWeight) Less(i, j int) bool { return b.weights[i] > b.weights[j] }
+func (b byWeight) Swap(i, j int) {
+	b.types[i], b.types[j] = b.types[j], b.types[i]
+	b.weights[i], b.weights[j] = b.weights[j], b.weights[i]
+}
+
+// jsonCodec implementation.
+type jsonCodec struct{}
+
+// Name implementation.
+func (jsonCodec) Name() string {
+	return "JSON"
+}
+
+// ContentType implementation.
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+// prettyJSON is the configuration of pretty printed JSON.
+var prettyJSON = jsoniter.Config{
+	EscapeHTML:             true,
+	SortMapKeys:            true,
+	ValidateJsonRawMessage: true,
+	IndentionStep:          2,
+}.Froze()
+
+// Encode implementation.
+func (jsonCodec) Encode(w io.Writer, v interface{}) error {
+	return encodeJSON(json, w, v)
+}
+
+// EncodePretty implementation.
+func (jsonCodec) EncodePretty(w io.Writer, v interface{}) error {
+	return encodeJSON(prettyJSON, w, v)
+}
+
+// encodeJSON writes the encoding of v followed by a newline to w, using a
+// pooled stream of the given configuration.
+func encodeJSON(api jsoniter.API, w io.Writer, v interface{}) error {
+	s := api.BorrowStream(w)
+	defer api.ReturnStream(s)
+
+	s.WriteVal(v)
+	s.WriteRaw("\n")
+	if s.Error != nil {
+		return s.Error
+	}
+
+	// Stream.Flush reslices the buffer, shrinking its capacity
+	_, err := w.Write(s.Buffer())
+	return err
+}
+
+// Decode implementation.
+func (jsonCodec) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// Unmarshal implementation.
+func (jsonCodec) Unmarshal(b []byte, v interface{}) error {
+	return json.Unmarshal(b, v)
+}
+
+// msgpackCodec implementation.
+type msgpackCodec struct{}
+
+// Name implementation.
+func (msgpackCodec) Name() string {
+	return "MessagePack"
+}
+
+// ContentType implementation.
+func (msgpackCodec) ContentType() string {
+	return "application/msgpack"
+}
+
+// Encode implementation.
+func (msgpackCodec) Encode(w io.Writer, v interface{}) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(v)
+}
+
+// Decode implementation.
+func (msgpackCodec) Decode(r io.Reader, v interface{}) error {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// cborEncMode encodes timestamps as RFC 3339 strings to match JSON.
+var cborEncMode, _ = cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+
+// cborDecMode lifts the decoder's structural limits in favour of Limits.
+var cborDecMode, _ = cbor.DecOptions{
+	MaxNestedLevels:  65535,
+	MaxArrayElements: 2147483647,
+	MaxMapPairs:      2147483647,
+}.DecMode()
+
+// cborCodec implementation.
+type cborCodec struct{}
+
+// Name implementation.
+func (cborCodec) Name() string {
+	return "CBOR"
+}
+
+// ContentType implementation.
+func (cborCodec) ContentType() string {
+	return "application/cbor"
+}
+
+// Encode implementation.
+func (cborCodec) Encode(w io.Writer, v interface{}) error {
+	return cborEncMode.NewEncoder(w).Encode(v)
+}
+
+// Decode implementation.
+func (cborCodec) Decode(r io.Reader, v interface{}) error {
+	return cborDecMode.NewDecoder(r).Decode(v)
+}
@@ -0,0 +1,112 @@
+package rpc_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// Test codec lookups.
+func TestLookupCodec(t *testing.T) {
+	t.Run("with a registered type", func(t *testing.T) {
+		c, ok := rpc.LookupCodec("application/msgpack")
+		assert.True(t, ok)
+		assert.Equal(t, rpc.MessagePack, c)
+	})
+
+	t.Run("with parameters", func(t *testing.T) {
+		c, ok := rpc.LookupCodec("application/cbor; charset=utf-8")
+		assert.True(t, ok)
+		assert.Equal(t, rpc.CBOR, c)
+	})
+
+	t.Run("with an unknown type", func(t *testing.T) {
+		_, ok := rpc.LookupCodec("text/plain")
+		assert.False(t, ok)
+	})
+}
+
+// Test codec negotiation.
+func TestNegotiateCodec(t *testing.T) {
+	t.Run("without headers", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		assert.Equal(t, rpc.JSON, rpc.NegotiateCodec(r))
+	})
+
+	t.Run("with an Accept header", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept", "application/cbor")
+		assert.Equal(t, rpc.CBOR, rpc.NegotiateCodec(r))
+	})
+
+	t.Run("with an Accept header using quality values", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept", "application/json;q=0.5, application/msgpack, text/html")
+		assert.Equal(t, rpc.MessagePack, rpc.NegotiateCodec(r))
+	})
+
+	t.Run("with a wildcard Accept header", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept", "*/*")
+		r.Header.Set("Content-Type", "application/msgpack")
+		assert.Equal(t, rpc.MessagePack, rpc.NegotiateCodec(r))
+	})
+}
+
+// Test negotiated responses.
+func TestNegotiate(t *testing.T) {
+	type pet struct {
+		Name string `json:"name"`
+	}
+
+	for _, c := range []rpc.Codec{rpc.JSON, rpc.MessagePack, rpc.CBOR} {
+		t.Run(c.Name(), func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", nil)
+			r.Header.Set("Accept", c.ContentType())
+
+			t.Run("with a response", func(t *testing.T) {
+				w := httptest.NewRecorder()
+				rpc.WriteResponse(rpc.Negotiate(w, r), pet{Name: "Tobi"})
+				assert.Equal(t, 200, w.Code)
+				assert.Equal(t, c.ContentType(), w.Header().Get("Content-Type"))
+				assert.Equal(t, "Accept", w.Header().Get("Vary"))
+
+				var out pet
+				assert.NoError(t, c.Decode(w.Body, &out))
+				assert.Equal(t, "Tobi", out.Name)
+			})
+
+			t.Run("with an error", func(t *testing.T) {
+				w := httptest.NewRecorder()
+				rpc.WriteError(rpc.Negotiate(w, r), errors.New("boom"))
+				assert.Equal(t, 500, w.Code)
+				assert.Equal(t, c.ContentType(), w.Header().Get("Content-Type"))
+
+				var out struct {
+					Type    string `json:"type"`
+					Message string `json:"message"`
+				}
+				assert.NoError(t, c.Decode(w.Body, &out))
+				assert.Equal(t, "internal", out.Type)
+				assert.Equal(t, "Internal server error", out.Message)
+			})
+
+			t.Run("with a request", func(t *testing.T) {
+				var buf bytes.Buffer
+				assert.NoError(t, c.Encode(&buf, pet{Name: "Loki"}))
+
+				r := httptest.NewRequest("POST", "/", &buf)
+				r.Header.Set("Content-Type", c.ContentType())
+
+				var in pet
+				assert.NoError(t, rpc.ReadRequest(r, &in))
+				assert.Equal(t, "Loki", in.Name)
+			})
+		})
+	}
+}
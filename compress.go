@@ -0,0 +1,227 @@
+package rpc
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressionThreshold is the minimum size in bytes of an encoded response
+// body before it is compressed.
+var CompressionThreshold = 1024
+
+// Compressor is the interface used for compressing and decompressing bodies.
+type Compressor interface {
+	// Encoding returns the Content-Encoding token handled by the compressor.
+	Encoding() string
+
+	// NewReader returns a reader decompressing r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+
+	// NewWriter returns a writer compressing to w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// Compressors available by default.
+var (
+	Gzip Compressor = gzipCompressor{}
+	Zstd Compressor = zstdCompressor{}
+)
+
+// compressors is the registry of compressors in order of server preference.
+var compressors = []Compressor{Zstd, Gzip}
+
+// RegisterCompressor registers c for its encoding, replacing any existing
+// compressor. New encodings are preferred over the existing ones. It is not
+// safe for concurrent use and should be called during initialization.
+func RegisterCompressor(c Compressor) {
+	for i, v := range compressors {
+		if v.Encoding() == c.Encoding() {
+			compressors[i] = c
+			return
+		}
+	}
+
+	compressors = append([]Compressor{c}, compressors...)
+}
+
+// LookupCompressor returns the compressor registered for the given encoding.
+func LookupCompressor(encoding string) (Compressor, bool) {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	for _, c := range compressors {
+		if c.Encoding() == encoding {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// NegotiateCompressor returns the compressor preferred by the Accept-Encoding
+// header of r, or nil when the response should not be compressed. The "*"
+// wildcard matches the preferred compressor which is not listed explicitly,
+// as listed encodings have their own quality, or are refused with q=0.
+func NegotiateCompressor(r *http.Request) Compressor {
+	header := r.Header.Get("Accept-Encoding")
+
+	for _, e := range acceptedTypes(header) {
+		if e == "*" {
+			for _, c := range compressors {
+				if !listedEncoding(header, c.Encoding()) {
+					return c
+				}
+			}
+			continue
+		}
+
+		if c, ok := LookupCompressor(e); ok {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// listedEncoding returns true if encoding is listed in the Accept-Encoding
+// header, with any quality.
+func listedEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		e, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && strings.EqualFold(e, encoding) {
+			return true
+		}
+	}
+	return false
+}
+
+// decompress returns the body of r decompressed according to its
+// Content-Encoding, or nil when it is not compressed.
+func decompress(r *http.Request) (io.ReadCloser, error) {
+	encoding := headerValue(r.Header, "Content-Encoding")
+
+	if encoding == "" || encoding == "identity" {
+		return nil, nil
+	}
+
+	c, ok := LookupCompressor(encoding)
+	if !ok {
+		return nil, BadRequest("Unsupported request Content-Encoding, must be " + supportedEncodings())
+	}
+
+	body, err := c.NewReader(r.Body)
+	if err != nil {
+		return nil, BadRequest("Failed to decompress malformed request body")
+	}
+
+	return body, nil
+}
+
+// supportedEncodings returns a formatted list of the registered encodings.
+func supportedEncodings() string {
+	var encodings []string
+	for _, c := range compressors {
+		encodings = append(encodings, c.Encoding())
+	}
+
+	switch len(encodings) {
+	case 1:
+		return encodings[0]
+	default:
+		return strings.Join(encodings[:len(encodings)-1], ", ") + " or " + encodings[len(encodings)-1]
+	}
+}
+
+// gzipCompressor implementation.
+type gzipCompressor struct{}
+
+// Encoding implementation.
+func (gzipCompressor) Encoding() string {
+	return "gzip"
+}
+
+// NewReader implementation.
+func (gzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// NewWriter implementation.
+func (gzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return pooled(&gzipWriters, w, func() (resetWriter, error) {
+		return gzip.NewWriter(w), nil
+	})
+}
+
+// zstdCompressor implementation.
+type zstdCompressor struct{}
+
+// Encoding implementation.
+func (zstdCompressor) Encoding() string {
+	return "zstd"
+}
+
+// NewReader implementation.
+func (zstdCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+// NewWriter implementation.
+func (zstdCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return pooled(&zstdWriters, w, func() (resetWriter, error) {
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	})
+}
+
+// Pools of compressing writers.
+var (
+	gzipWriters sync.Pool
+	zstdWriters sync.Pool
+)
+
+// resetWriter is a compressing writer which may be reused.
+type resetWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// pooled returns a writer from pool compressing to w, or a new one created
+// with fn, which is returned to the pool when it is closed.
+func pooled(pool *sync.Pool, w io.Writer, fn func() (resetWriter, error)) (io.WriteCloser, error) {
+	zw, ok := pool.Get().(resetWriter)
+	if ok {
+		zw.Reset(w)
+		return &pooledWriter{zw, pool}, nil
+	}
+
+	zw, err := fn()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pooledWriter{zw, pool}, nil
+}
+
+// pooledWriter is a compressing writer returned to its pool when closed.
+type pooledWriter struct {
+	resetWriter
+	pool *sync.Pool
+}
+
+// Close implementation.
+func (w *pooledWriter) Close() error {
+	if w.resetWriter == nil {
+		return nil
+	}
+
+	err := w.resetWriter.Close()
+	w.pool.Put(w.resetWriter)
+	w.resetWriter = nil
+	return err
+}
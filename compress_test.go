@@ -0,0 +1,146 @@
+package rpc_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// compress returns s compressed with c.
+func compress(t testing.TB, c rpc.Compressor, s string) *bytes.Buffer {
+	var buf bytes.Buffer
+	w, err := c.NewWriter(&buf)
+	assert.NoError(t, err)
+	_, err = w.Write([]byte(s))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return &buf
+}
+
+// Test compressor negotiation.
+func TestNegotiateCompressor(t *testing.T) {
+	t.Run("without an Accept-Encoding header", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		assert.Nil(t, rpc.NegotiateCompressor(r))
+	})
+
+	t.Run("with an Accept-Encoding header", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip, deflate, br")
+		assert.Equal(t, rpc.Gzip, rpc.NegotiateCompressor(r))
+	})
+
+	t.Run("with an Accept-Encoding header using quality values", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip;q=0.5, zstd")
+		assert.Equal(t, rpc.Zstd, rpc.NegotiateCompressor(r))
+	})
+
+	t.Run("with a wildcard Accept-Encoding header", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept-Encoding", "*")
+		assert.Equal(t, rpc.Zstd, rpc.NegotiateCompressor(r))
+	})
+
+	t.Run("with a wildcard Accept-Encoding header and refused encodings", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept-Encoding", "zstd;q=0, *")
+		assert.Equal(t, rpc.Gzip, rpc.NegotiateCompressor(r))
+
+		r.Header.Set("Accept-Encoding", "gzip;q=0, *")
+		assert.Equal(t, rpc.Zstd, rpc.NegotiateCompressor(r))
+
+		r.Header.Set("Accept-Encoding", "gzip;q=0, zstd;q=0, *")
+		assert.Nil(t, rpc.NegotiateCompressor(r))
+	})
+
+	t.Run("with only unsupported encodings", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept-Encoding", "br, gzip;q=0")
+		assert.Nil(t, rpc.NegotiateCompressor(r))
+	})
+}
+
+// Test compressed requests.
+func TestReadRequest_compression(t *testing.T) {
+	for _, c := range []rpc.Compressor{rpc.Gzip, rpc.Zstd} {
+		t.Run(c.Encoding(), func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", compress(t, c, `{ "name": "Tobi" }`))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Content-Encoding", c.Encoding())
+			var in struct{ Name string }
+			err := rpc.ReadRequest(r, &in)
+			assert.NoError(t, err, "parsing")
+			assert.Equal(t, "Tobi", in.Name)
+		})
+	}
+
+	t.Run("with an unsupported encoding", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "name": "Tobi" }`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Content-Encoding", "br")
+		var in struct{ Name string }
+		err := rpc.ReadRequest(r, &in)
+		assert.EqualError(t, err, `Unsupported request Content-Encoding, must be zstd or gzip`)
+	})
+
+	t.Run("with a malformed body", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "name": "Tobi" }`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Content-Encoding", "gzip")
+		var in struct{ Name string }
+		err := rpc.ReadRequest(r, &in)
+		assert.EqualError(t, err, `Failed to decompress malformed request body`)
+	})
+}
+
+// Test compressed responses.
+func TestWriteResponse_compression(t *testing.T) {
+	type pets struct {
+		Names []string `json:"names"`
+	}
+
+	large := pets{Names: make([]string, 500)}
+	for i := range large.Names {
+		large.Names[i] = "Tobi"
+	}
+
+	for _, c := range []rpc.Compressor{rpc.Gzip, rpc.Zstd} {
+		t.Run(c.Encoding(), func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", nil)
+			r.Header.Set("Accept-Encoding", c.Encoding())
+
+			t.Run("above the threshold", func(t *testing.T) {
+				w := httptest.NewRecorder()
+				rpc.WriteResponse(rpc.Negotiate(w, r), large)
+				assert.Equal(t, 200, w.Code)
+				assert.Equal(t, c.Encoding(), w.Header().Get("Content-Encoding"))
+				assert.Equal(t, strconv.Itoa(w.Body.Len()), w.Header().Get("Content-Length"))
+
+				body, err := c.NewReader(w.Body)
+				assert.NoError(t, err)
+				b, err := ioutil.ReadAll(body)
+				assert.NoError(t, err)
+
+				var out pets
+				assert.NoError(t, rpc.JSON.Decode(bytes.NewReader(b), &out))
+				assert.Len(t, out.Names, 500)
+			})
+
+			t.Run("below the threshold", func(t *testing.T) {
+				w := httptest.NewRecorder()
+				rpc.WriteResponse(rpc.Negotiate(w, r), pets{Names: []string{"Tobi"}})
+				assert.Equal(t, 200, w.Code)
+				assert.Equal(t, "", w.Header().Get("Content-Encoding"))
+				assert.Equal(t, "{\"names\":[\"Tobi\"]}", strings.TrimSpace(w.Body.String()))
+			})
+		})
+	}
+}
@@ -8,9 +8,14 @@ import (
 // ctxKey is a private context key.
 type ctxKey struct{}
 
-// NewRequestContext returns a new context with ctx.
+// requestIDKey is a private context key for the request id.
+type requestIDKey struct{}
+
+// NewRequestContext returns a new context with ctx, and the request id of v,
+// which is generated when v has none.
 func NewRequestContext(ctx context.Context, v *http.Request) context.Context {
-	return context.WithValue(ctx, ctxKey{}, v)
+	ctx = context.WithValue(ctx, ctxKey{}, v)
+	return context.WithValue(ctx, requestIDKey{}, RequestID(v))
 }
 
 // RequestFromContext returns ctx from context.
@@ -18,3 +23,39 @@ func RequestFromContext(ctx context.Context) (*http.Request, bool) {
 	v, ok := ctx.Value(ctxKey{}).(*http.Request)
 	return v, ok
 }
+
+// RequestIDFromContext returns the request id from context, or an empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(requestIDKey{}).(string)
+	return v
+}
+
+// RequestID returns the X-Request-Id header of r. A new id is generated and
+// set on r when the header is missing or invalid, so the id is stable for
+// the request.
+func RequestID(r *http.Request) string {
+	id := r.Header.Get("X-Request-Id")
+	if validRequestID(id) {
+		return id
+	}
+
+	id = randomID(16)
+	r.Header.Set("X-Request-Id", id)
+	return id
+}
+
+// validRequestID returns true if id is a non-empty string of at most
+// 128 printable ASCII characters.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,54 @@
+package rpc_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// Test request ids.
+func TestRequestID(t *testing.T) {
+	t.Run("with a request id", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("X-Request-Id", "abc")
+		assert.Equal(t, "abc", rpc.RequestID(r))
+	})
+
+	t.Run("without a request id", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		id := rpc.RequestID(r)
+		assert.Len(t, id, 32)
+		assert.Equal(t, id, r.Header.Get("X-Request-Id"))
+		assert.Equal(t, id, rpc.RequestID(r))
+	})
+
+	t.Run("with an invalid request id", func(t *testing.T) {
+		for _, v := range []string{"a b", "é", strings.Repeat("a", 129)} {
+			r := httptest.NewRequest("POST", "/", nil)
+			r.Header.Set("X-Request-Id", v)
+			assert.NotEqual(t, v, rpc.RequestID(r))
+		}
+	})
+
+	t.Run("with a response", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		w := httptest.NewRecorder()
+		rpc.WriteResponse(rpc.Negotiate(w, r), nil)
+		assert.Equal(t, r.Header.Get("X-Request-Id"), w.Header().Get("X-Request-Id"))
+		assert.NotEmpty(t, w.Header().Get("X-Request-Id"))
+	})
+}
+
+// Test request ids in context.
+func TestRequestIDFromContext(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("X-Request-Id", "abc")
+	ctx := rpc.NewRequestContext(context.Background(), r)
+	assert.Equal(t, "abc", rpc.RequestIDFromContext(ctx))
+	assert.Equal(t, "", rpc.RequestIDFromContext(context.Background()))
+}
@@ -0,0 +1,176 @@
+package rpc
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS is the cross-origin resource sharing configuration of a server.
+type CORS struct {
+	// AllowedOrigins is the list of origins allowed to call the server, such
+	// as "https://example.com". The origin "*" allows any origin, and a "*"
+	// in the host allows any subdomain, as in "https://*.example.com".
+	AllowedOrigins []string
+
+	// AllowedHeaders is the list of request headers allowed in addition to
+	// those used by the generated clients.
+	AllowedHeaders []string
+
+	// ExposedHeaders is the list of response headers exposed in addition to
+	// those used by the generated clients.
+	ExposedHeaders []string
+
+	// AllowCredentials allows requests with credentials such as cookies.
+	AllowCredentials bool
+
+	// MaxAge is the duration preflight responses may be cached for.
+	MaxAge time.Duration
+}
+
+// CORSProvider is the interface used for servers providing a CORS configuration.
+type CORSProvider interface {
+	CORS() *CORS
+}
+
+// DefaultCORS is the CORS configuration of servers which do not provide one,
+// cross-origin requests are not allowed when it is nil.
+var DefaultCORS *CORS
+
+// corsAllowedHeaders are the request headers used by the generated clients.
+var corsAllowedHeaders = []string{
+	"Accept",
+	"Authorization",
+	"Content-Encoding",
+	"Content-Type",
+	"Idempotency-Key",
+	"Rpc-Timeout",
+	"Traceparent",
+	"X-Request-Id",
+}
+
+// corsExposedHeaders are the response headers used by the generated clients.
+var corsExposedHeaders = []string{
+	"Idempotent-Replayed",
+	"RateLimit-Limit",
+	"RateLimit-Remaining",
+	"RateLimit-Reset",
+	"Retry-After",
+	"X-Request-Id",
+}
+
+// corsMethods are the methods served by the generated servers.
+const corsMethods = "GET, POST"
+
+// HandleCORS sets the CORS response headers for requests from the allowed
+// origins, returning true when r is a preflight request which has been
+// responded to.
+//
+// The configuration is provided by s when it implements CORSProvider,
+// otherwise DefaultCORS is used.
+func HandleCORS(w http.ResponseWriter, r *http.Request, s interface{}) bool {
+	c := DefaultCORS
+	if p, ok := s.(CORSProvider); ok {
+		c = p.CORS()
+	}
+
+	if c == nil {
+		return false
+	}
+
+	w.Header().Add("Vary", "Origin")
+
+	origin := r.Header.Get("Origin")
+	if origin == "" || !c.allowOrigin(origin) {
+		return false
+	}
+
+	h := w.Header()
+	if c.AllowCredentials || !c.allowAnyOrigin() {
+		h.Set("Access-Control-Allow-Origin", origin)
+	} else {
+		h.Set("Access-Control-Allow-Origin", "*")
+	}
+
+	if c.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	method := r.Header.Get("Access-Control-Request-Method")
+	if r.Method != http.MethodOptions || method == "" {
+		h.Set("Access-Control-Expose-Headers", strings.Join(append(corsExposedHeaders, c.ExposedHeaders...), ", "))
+		return false
+	}
+
+	// preflight
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	h.Set("Access-Control-Allow-Methods", corsMethods)
+
+	if headers := c.allowedHeaders(r.Header.Get("Access-Control-Request-Headers")); len(headers) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+
+	if c.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// allowAnyOrigin returns true if any origin is allowed.
+func (c *CORS) allowAnyOrigin() bool {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// allowOrigin returns true if origin is allowed.
+func (c *CORS) allowOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+
+	for _, o := range c.AllowedOrigins {
+		o = strings.ToLower(o)
+
+		if o == "*" || o == origin {
+			return true
+		}
+
+		// subdomains
+		if i := strings.Index(o, "*"); i >= 0 {
+			prefix, suffix := o[:i], o[i+1:]
+			if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// allowedHeaders returns the allowed headers of the comma-separated list of
+// requested headers.
+func (c *CORS) allowedHeaders(requested string) (headers []string) {
+	allowed := append(corsAllowedHeaders, c.AllowedHeaders...)
+
+	for _, name := range strings.Split(requested, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		for _, a := range allowed {
+			if strings.EqualFold(a, name) {
+				headers = append(headers, name)
+				break
+			}
+		}
+	}
+
+	return headers
+}
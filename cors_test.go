@@ -0,0 +1,93 @@
+package rpc_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// corsServer is a server providing a CORS configuration.
+type corsServer struct {
+	cors *rpc.CORS
+}
+
+// CORS implementation.
+func (s corsServer) CORS() *rpc.CORS {
+	return s.cors
+}
+
+// Test CORS.
+func TestHandleCORS(t *testing.T) {
+	s := corsServer{&rpc.CORS{
+		AllowedOrigins:   []string{"https://example.com", "https://*.apex.sh"},
+		AllowedHeaders:   []string{"X-Client-Version"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}}
+
+	t.Run("without a configuration", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("OPTIONS", "/add_item", nil)
+		r.Header.Set("Origin", "https://example.com")
+		r.Header.Set("Access-Control-Request-Method", "POST")
+		assert.False(t, rpc.HandleCORS(w, r, struct{}{}))
+		assert.Empty(t, w.Header())
+	})
+
+	t.Run("with a request from an allowed origin", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/add_item", nil)
+		r.Header.Set("Origin", "https://example.com")
+		assert.False(t, rpc.HandleCORS(w, r, s))
+		assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), "X-Request-Id")
+		assert.Equal(t, "Origin", w.Header().Get("Vary"))
+	})
+
+	t.Run("with a request from a subdomain", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/add_item", nil)
+		r.Header.Set("Origin", "https://app.apex.sh")
+		rpc.HandleCORS(w, r, s)
+		assert.Equal(t, "https://app.apex.sh", w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("with a request from another origin", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/add_item", nil)
+		r.Header.Set("Origin", "https://apex.sh.evil.com")
+		assert.False(t, rpc.HandleCORS(w, r, s))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("with a preflight request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("OPTIONS", "/add_item", nil)
+		r.Header.Set("Origin", "https://example.com")
+		r.Header.Set("Access-Control-Request-Method", "POST")
+		r.Header.Set("Access-Control-Request-Headers", "content-type, authorization, x-client-version, x-other")
+		assert.True(t, rpc.HandleCORS(w, r, s))
+		assert.Equal(t, 204, w.Code)
+		assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "content-type, authorization, x-client-version", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "3600", w.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("with any origin", func(t *testing.T) {
+		rpc.DefaultCORS = &rpc.CORS{AllowedOrigins: []string{"*"}}
+		defer func() { rpc.DefaultCORS = nil }()
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/add_item", nil)
+		r.Header.Set("Origin", "https://example.com")
+		rpc.HandleCORS(w, r, struct{}{})
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	})
+}
@@ -0,0 +1,252 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// decodeError returns the error of the body b which failed to decode into
+// value with c. JSON syntax errors are reported with their byte offset, and
+// values which do not match the type of their field as ValidationErrors
+// naming the expected schema type, otherwise a generic error is returned.
+func decodeError(c Codec, b []byte, value interface{}) error {
+	malformed := BadRequest(fmt.Sprintf("Failed to parse malformed request body, must be a valid %s object", c.Name()))
+
+	var v interface{}
+	if c.ContentType() == JSON.ContentType() {
+		var se *stdjson.SyntaxError
+		if err := stdjson.Unmarshal(b, &v); errors.As(err, &se) {
+			msg := fmt.Sprintf("Failed to parse malformed request body, %s at byte offset %d", se, se.Offset)
+			details := struct {
+				Offset int64 `json:"offset"`
+			}{se.Offset}
+			return Error(http.StatusBadRequest, "bad_request", msg, WithDetails(details))
+		}
+
+		// decode again preserving numbers to check integers precisely
+		dec := stdjson.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return malformed
+		}
+	} else if err := c.Decode(bytes.NewReader(b), &v); err != nil {
+		return malformed
+	}
+
+	t := reflect.TypeOf(value)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// the body must be an object to report its fields
+	if t == nil || !isObject(v) {
+		return malformed
+	}
+
+	errs := typeErrors("", "", v, t)
+	if len(errs) == 0 {
+		return malformed
+	}
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Path < errs[j].Path
+	})
+
+	return errs
+}
+
+// timeType is the type of time.Time, which is a timestamp in schemas.
+var timeType = reflect.TypeOf(time.Time{})
+
+// typeErrors returns the errors of the decoded value v at path, of the given
+// field, which does not match the type t.
+func typeErrors(path, field string, v interface{}, t reflect.Type) (errs ValidationErrors) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// null values decode to the zero value
+	if v == nil {
+		return nil
+	}
+
+	mismatch := func(kind string) ValidationErrors {
+		return ValidationErrors{{
+			Path:    path,
+			Field:   field,
+			Message: "must be of type " + kind,
+		}}
+	}
+
+	// types decoding themselves
+	if t == timeType {
+		s, ok := v.(string)
+		if _, err := time.Parse(time.RFC3339Nano, s); !ok || err != nil {
+			return mismatch("timestamp")
+		}
+		return nil
+	}
+
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		s, ok := v.(string)
+		if !ok {
+			return mismatch("string")
+		}
+		if err := reflect.New(t).Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return ValidationErrors{{Path: path, Field: field, Message: err.Error()}}
+		}
+		return nil
+	}
+
+	if reflect.PtrTo(t).Implements(jsonUnmarshalerType) {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		if _, ok := v.(string); !ok {
+			return mismatch("string")
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			return mismatch("boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := toInt(v)
+		if !ok || reflect.Zero(t).OverflowInt(n) {
+			return mismatch("integer")
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := toUint(v)
+		if !ok || reflect.Zero(t).OverflowUint(n) {
+			return mismatch("integer")
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := toFloat(v); !ok {
+			return mismatch("float")
+		}
+	case reflect.Struct:
+		if !isObject(v) {
+			return mismatch("object")
+		}
+		fields := structFields(t)
+		eachKey(v, func(key string, v interface{}) {
+			if f, ok := lookupField(fields, key); ok {
+				errs = append(errs, typeErrors(path+"/"+escapePointer(key), key, v, f.Type)...)
+			}
+		})
+	case reflect.Map:
+		if !isObject(v) {
+			return mismatch("object")
+		}
+		eachKey(v, func(key string, v interface{}) {
+			errs = append(errs, typeErrors(path+"/"+escapePointer(key), key, v, t.Elem())...)
+		})
+	case reflect.Slice, reflect.Array:
+		// bytes are encoded as base64 strings
+		if t.Elem().Kind() == reflect.Uint8 {
+			switch v.(type) {
+			case string, []byte:
+				return nil
+			}
+			return mismatch("string")
+		}
+		items, ok := v.([]interface{})
+		if !ok {
+			return mismatch("array")
+		}
+		for i, item := range items {
+			errs = append(errs, typeErrors(path+"/"+strconv.Itoa(i), field, item, t.Elem())...)
+		}
+	}
+
+	return errs
+}
+
+// isObject returns true if the decoded value v is an object.
+func isObject(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// toFloat returns the decoded number v as a float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case stdjson.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	default:
+		return 0, false
+	}
+}
+
+// toInt returns the decoded number v as an int64, if it is an integer in range.
+func toInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case stdjson.Number:
+		i, err := strconv.ParseInt(string(n), 10, 64)
+		return i, err == nil
+	case float64:
+		return int64(n), n == math.Trunc(n) && n >= math.MinInt64 && n < math.MaxInt64
+	case float32:
+		return toInt(float64(n))
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), rv.Uint() <= math.MaxInt64
+	default:
+		return 0, false
+	}
+}
+
+// toUint returns the decoded number v as a uint64, if it is an integer in range.
+func toUint(v interface{}) (uint64, bool) {
+	switch n := v.(type) {
+	case stdjson.Number:
+		i, err := strconv.ParseUint(string(n), 10, 64)
+		return i, err == nil
+	case float64:
+		return uint64(n), n == math.Trunc(n) && n >= 0 && n < math.MaxUint64
+	case float32:
+		return toUint(float64(n))
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(rv.Int()), rv.Int() >= 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), true
+	default:
+		return 0, false
+	}
+}
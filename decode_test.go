@@ -0,0 +1,84 @@
+package rpc_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/apex/rpc"
+)
+
+// decodeInput is an input with fields of each schema type.
+type decodeInput struct {
+	ID        int                `json:"id"`
+	Count     uint8              `json:"count"`
+	Price     float64            `json:"price"`
+	Done      bool               `json:"done"`
+	Text      string             `json:"text"`
+	CreatedAt time.Time          `json:"created_at"`
+	Tags      []string           `json:"tags"`
+	Meta      map[string]int     `json:"meta"`
+	Items     []struct{ ID int } `json:"items"`
+}
+
+// Test decode errors.
+func TestReadRequest_decodeErrors(t *testing.T) {
+	read := func(body string) error {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		var in decodeInput
+		return rpc.ReadRequest(r, &in)
+	}
+
+	t.Run("with a syntax error", func(t *testing.T) {
+		err := read(`{ "id": 1, }`)
+		assert.EqualError(t, err, `Failed to parse malformed request body, invalid character '}' looking for beginning of object key string at byte offset 12`)
+		assert.Equal(t, "bad_request", err.(rpc.TypeProvider).Type())
+
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, err)
+		assert.Contains(t, w.Body.String(), `"details":{"offset":12}`)
+	})
+
+	t.Run("with a type mismatch", func(t *testing.T) {
+		err := read(`{ "id": "5" }`)
+		assert.Equal(t, rpc.ValidationErrors{
+			{Path: "/id", Field: "id", Message: "must be of type integer"},
+		}, err)
+		assert.EqualError(t, err, `id must be of type integer`)
+	})
+
+	t.Run("with type mismatches", func(t *testing.T) {
+		err := read(`{ "id": 1.5, "count": 300, "price": "1", "done": 1, "text": 5, "created_at": "yesterday", "tags": "a", "meta": { "a": true }, "items": [{ "ID": 1 }, { "ID": null }, { "ID": [] }] }`)
+		assert.Equal(t, rpc.ValidationErrors{
+			{Path: "/count", Field: "count", Message: "must be of type integer"},
+			{Path: "/created_at", Field: "created_at", Message: "must be of type timestamp"},
+			{Path: "/done", Field: "done", Message: "must be of type boolean"},
+			{Path: "/id", Field: "id", Message: "must be of type integer"},
+			{Path: "/items/2/ID", Field: "ID", Message: "must be of type integer"},
+			{Path: "/meta/a", Field: "a", Message: "must be of type integer"},
+			{Path: "/price", Field: "price", Message: "must be of type float"},
+			{Path: "/tags", Field: "tags", Message: "must be of type array"},
+			{Path: "/text", Field: "text", Message: "must be of type string"},
+		}, err)
+	})
+
+	t.Run("with a body which is not an object", func(t *testing.T) {
+		err := read(`[]`)
+		assert.EqualError(t, err, `Failed to parse malformed request body, must be a valid JSON object`)
+	})
+
+	t.Run("with a MessagePack body", func(t *testing.T) {
+		b, _ := msgpack.Marshal(map[string]interface{}{"id": "5"})
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+		r.Header.Set("Content-Type", "application/msgpack")
+		var in decodeInput
+		err := rpc.ReadRequest(r, &in)
+		assert.EqualError(t, err, `id must be of type integer`)
+	})
+}
@@ -1,7 +1,12 @@
 package rpc
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // StatusProvider is the interface used for providing an HTTP status code.
@@ -14,11 +19,35 @@ type TypeProvider interface {
 	Type() string
 }
 
-// ServerError is a server error which implements StatusProvider and TypeProvider.
+// DetailsProvider is the interface used for providing structured error details.
+type DetailsProvider interface {
+	ErrorDetails() interface{}
+}
+
+// HeaderProvider is the interface used for providing response headers.
+type HeaderProvider interface {
+	ErrorHeader() http.Header
+}
+
+// RetryProvider is the interface used for providing retry hints.
+type RetryProvider interface {
+	Retryable() bool
+	RetryAfter() time.Duration
+}
+
+// Debug enables exposing the messages of internal errors in responses.
+var Debug = false
+
+// ServerError is a server error which implements StatusProvider, TypeProvider,
+// DetailsProvider, HeaderProvider and RetryProvider.
 type ServerError struct {
-	status  int
-	kind    string
-	message string
+	status     int
+	kind       string
+	message    string
+	details    interface{}
+	header     http.Header
+	retryable  bool
+	retryAfter time.Duration
 }
 
 // StatusCode implementation.
@@ -36,13 +65,71 @@ func (e ServerError) Error() string {
 	return e.message
 }
 
+// ErrorDetails implementation.
+func (e ServerError) ErrorDetails() interface{} {
+	return e.details
+}
+
+// ErrorHeader implementation.
+func (e ServerError) ErrorHeader() http.Header {
+	return e.header
+}
+
+// Retryable implementation.
+func (e ServerError) Retryable() bool {
+	return e.retryable
+}
+
+// RetryAfter implementation.
+func (e ServerError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// ErrorOption is a ServerError option.
+type ErrorOption func(*ServerError)
+
+// WithDetails sets structured details, which must be encodable by the codecs.
+func WithDetails(v interface{}) ErrorOption {
+	return func(e *ServerError) {
+		e.details = v
+	}
+}
+
+// WithHeader sets response headers.
+func WithHeader(h http.Header) ErrorOption {
+	return func(e *ServerError) {
+		e.header = h
+	}
+}
+
+// WithRetryable marks the error as retryable.
+func WithRetryable() ErrorOption {
+	return func(e *ServerError) {
+		e.retryable = true
+	}
+}
+
+// WithRetryAfter marks the error as retryable after d.
+func WithRetryAfter(d time.Duration) ErrorOption {
+	return func(e *ServerError) {
+		e.retryable = true
+		e.retryAfter = d
+	}
+}
+
 // Error returns a new ServerError with HTTP status code, kind and message.
-func Error(status int, kind, message string) error {
-	return ServerError{
+func Error(status int, kind, message string, options ...ErrorOption) error {
+	e := ServerError{
 		kind:    kind,
 		status:  status,
 		message: message,
 	}
+
+	for _, o := range options {
+		o(&e)
+	}
+
+	return e
 }
 
 // BadRequest returns a new bad request error.
@@ -55,42 +142,161 @@ func Invalid(message string) error {
 	return Error(http.StatusBadRequest, "invalid", message)
 }
 
+// RequestTooLarge returns a new request too large error.
+func RequestTooLarge(message string) error {
+	return Error(http.StatusRequestEntityTooLarge, "request_too_large", message)
+}
+
+// TooDeep returns a new error for excessively nested input.
+func TooDeep(message string) error {
+	return Error(http.StatusBadRequest, "too_deep", message)
+}
+
+// Unauthorized returns a new unauthorized error.
+func Unauthorized(message string) error {
+	return Error(http.StatusUnauthorized, "unauthorized", message)
+}
+
+// Forbidden returns a new forbidden error.
+func Forbidden(message string) error {
+	return Error(http.StatusForbidden, "forbidden", message)
+}
+
+// MethodNotAllowed returns a new method not allowed error, with the Allow
+// header listing the allowed methods.
+func MethodNotAllowed(message string, allowed ...string) error {
+	h := http.Header{}
+	h.Set("Allow", strings.Join(allowed, ", "))
+	return Error(http.StatusMethodNotAllowed, "method_not_allowed", message, WithHeader(h))
+}
+
+// DeadlineExceeded returns a new deadline exceeded error.
+func DeadlineExceeded(message string) error {
+	return Error(http.StatusGatewayTimeout, "deadline_exceeded", message)
+}
+
 // serverErrorResponse is an error response.
 type serverErrorResponse struct {
-	Type    string `json:"type"`
-	Message string `json:"message"`
+	Type       string            `json:"type"`
+	Message    string            `json:"message"`
+	Fields     []ValidationError `json:"fields,omitempty"`
+	Details    interface{}       `json:"details,omitempty"`
+	Retryable  bool              `json:"retryable,omitempty"`
+	RetryAfter int64             `json:"retry_after,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
 }
 
 // WriteError writes an error.
 //
+// The error chain of err is searched for each of the
+// interfaces below, so errors may be wrapped with %w.
+//
 // If err is a StatusProvider the status code provided
 // is used, otherwise it defaults to StatusInternalServerError.
 //
 // If err is a TypeProvider the type provided is used,
 // otherwise it defaults to "internal".
 //
-// The message in the response uses the Error()
-// implementation.
+// If err is a FieldsProvider the field validation
+// errors are included in the response.
+//
+// If err is a DetailsProvider the details provided
+// are included in the response.
 //
+// If err is a RetryProvider the retry hints are included
+// in the response, with Retry-After set in seconds.
+//
+// If err is a HeaderProvider the headers provided are
+// set on the response.
+//
+// The message in the response uses the Error() implementation
+// of the first error in the chain providing a status or type.
+// Messages of other errors are replaced unless Debug is enabled.
+// The body is encoded with the codec negotiated by Negotiate,
+// defaulting to JSON, and includes the X-Request-Id response
+// header set by Negotiate.
 func WriteError(w http.ResponseWriter, err error) {
-	w.Header().Set("Content-Type", "application/json")
+	status, body := errorResponse(err, w.Header().Get("X-Request-Id"))
+	setErrorHeader(w, err, body)
+	write(w, status, body)
+}
 
-	if e, ok := err.(StatusProvider); ok {
-		w.WriteHeader(e.StatusCode())
-	} else {
-		w.WriteHeader(http.StatusInternalServerError)
+// setErrorHeader sets the response headers of err with the given body.
+func setErrorHeader(w http.ResponseWriter, err error, body serverErrorResponse) {
+	for k, v := range errorHeader(err, body) {
+		w.Header()[k] = v
 	}
+}
 
-	var body serverErrorResponse
+// errorHeader returns the response headers of err with the given body.
+func errorHeader(err error, body serverErrorResponse) http.Header {
+	h := http.Header{}
+
+	if e := HeaderProvider(nil); errors.As(err, &e) {
+		for k, v := range e.ErrorHeader() {
+			h[k] = v
+		}
+	}
+
+	if body.RetryAfter > 0 {
+		h.Set("Retry-After", strconv.FormatInt(body.RetryAfter, 10))
+	}
 
-	if e, ok := err.(TypeProvider); ok {
+	return h
+}
+
+// errorResponse returns the status code and response body for err of the request with the given id.
+func errorResponse(err error, requestID string) (int, serverErrorResponse) {
+	status := http.StatusInternalServerError
+	if e := StatusProvider(nil); errors.As(err, &e) {
+		status = e.StatusCode()
+	}
+
+	body := serverErrorResponse{
+		RequestID: requestID,
+	}
+
+	if e := TypeProvider(nil); errors.As(err, &e) {
 		body.Type = e.Type()
 	} else {
 		body.Type = "internal"
 	}
 
-	body.Message = err.Error()
-	enc := json.NewEncoder(w)
-	enc.SetIndent("", "  ")
-	enc.Encode(body)
+	if e := FieldsProvider(nil); errors.As(err, &e) {
+		body.Fields = e.Fields()
+	}
+
+	if e := DetailsProvider(nil); errors.As(err, &e) {
+		body.Details = e.ErrorDetails()
+	}
+
+	if e := RetryProvider(nil); errors.As(err, &e) && e.Retryable() {
+		body.Retryable = true
+		if d := e.RetryAfter(); d > 0 {
+			body.RetryAfter = int64(math.Ceil(d.Seconds()))
+		}
+	}
+
+	switch e := publicError(err); {
+	case e != nil:
+		body.Message = e.Error()
+	case Debug:
+		body.Message = err.Error()
+	default:
+		body.Message = "Internal server error"
+	}
+
+	return status, body
+}
+
+// publicError returns the first error in the chain of err
+// providing a status or type, or nil.
+func publicError(err error) error {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch err.(type) {
+		case StatusProvider, TypeProvider:
+			return err
+		}
+	}
+	return nil
 }
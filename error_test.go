@@ -2,15 +2,27 @@ package rpc_test
 
 import (
 	"errors"
+	"fmt"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/tj/assert"
 
 	"github.com/apex/rpc"
 )
 
+// notFoundError is an error with details.
+type notFoundError struct {
+	ID int `json:"id"`
+}
+
+func (e notFoundError) Error() string             { return "Item not found" }
+func (e notFoundError) StatusCode() int           { return 404 }
+func (e notFoundError) Type() string              { return "item_not_found" }
+func (e notFoundError) ErrorDetails() interface{} { return e }
+
 // Test error reporting.
 func TestWriteError(t *testing.T) {
 	t.Run("with a regular error", func(t *testing.T) {
@@ -18,14 +30,65 @@ func TestWriteError(t *testing.T) {
 		rpc.WriteError(w, errors.New("boom"))
 		assert.Equal(t, 500, w.Code)
 		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, "{\"type\":\"internal\",\"message\":\"Internal server error\"}", strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("with a request id", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("X-Request-Id", "abc")
+		w := httptest.NewRecorder()
+		rpc.WriteError(rpc.Negotiate(w, r), rpc.BadRequest("Nope"))
+		assert.Equal(t, 400, w.Code)
+		assert.Equal(t, "abc", w.Header().Get("X-Request-Id"))
+		assert.JSONEq(t, `{"type":"bad_request","message":"Nope","request_id":"abc"}`, w.Body.String())
+	})
+
+	t.Run("with a regular error in debug mode", func(t *testing.T) {
+		rpc.Debug = true
+		defer func() { rpc.Debug = false }()
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, errors.New("boom"))
+		assert.Equal(t, 500, w.Code)
 		assert.Equal(t, "{\n  \"type\": \"internal\",\n  \"message\": \"boom\"\n}", strings.TrimSpace(w.Body.String()))
 	})
 
+	t.Run("with a wrapped error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, fmt.Errorf("removing item: %w", rpc.Error(404, "not_found", "Item not found")))
+		assert.Equal(t, 404, w.Code)
+		assert.Equal(t, "{\"type\":\"not_found\",\"message\":\"Item not found\"}", strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("with details and retry hints", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		err := rpc.Error(503, "unavailable", "Try again later",
+			rpc.WithDetails(map[string]string{"region": "us-west-2"}),
+			rpc.WithRetryAfter(1500*time.Millisecond))
+		rpc.WriteError(w, err)
+		assert.Equal(t, 503, w.Code)
+		assert.Equal(t, "2", w.Header().Get("Retry-After"))
+		assert.Equal(t, "{\"type\":\"unavailable\",\"message\":\"Try again later\",\"details\":{\"region\":\"us-west-2\"},\"retryable\":true,\"retry_after\":2}", strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("with a retryable error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, rpc.Error(409, "conflict", "Conflict", rpc.WithRetryable()))
+		assert.Equal(t, "", w.Header().Get("Retry-After"))
+		assert.Equal(t, "{\"type\":\"conflict\",\"message\":\"Conflict\",\"retryable\":true}", strings.TrimSpace(w.Body.String()))
+	})
+
 	t.Run("with a TypeProvider", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		rpc.WriteError(w, rpc.Error(400, "invalid_slug", "Invalid team slug"))
 		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
-		assert.Equal(t, "{\n  \"type\": \"invalid_slug\",\n  \"message\": \"Invalid team slug\"\n}", strings.TrimSpace(w.Body.String()))
+		assert.Equal(t, "{\"type\":\"invalid_slug\",\"message\":\"Invalid team slug\"}", strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("with a DetailsProvider", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, notFoundError{ID: 5})
+		assert.Equal(t, 404, w.Code)
+		assert.Equal(t, "{\"type\":\"item_not_found\",\"message\":\"Item not found\",\"details\":{\"id\":5}}", strings.TrimSpace(w.Body.String()))
 	})
 
 	t.Run("with a StatusProvider", func(t *testing.T) {
@@ -34,4 +97,12 @@ func TestWriteError(t *testing.T) {
 		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
 		assert.Equal(t, 400, w.Code)
 	})
+
+	t.Run("with a method not allowed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, rpc.MethodNotAllowed("Method not allowed", "GET", "POST"))
+		assert.Equal(t, 405, w.Code)
+		assert.Equal(t, "GET, POST", w.Header().Get("Allow"))
+		assert.Equal(t, "{\"type\":\"method_not_allowed\",\"message\":\"Method not allowed\"}", strings.TrimSpace(w.Body.String()))
+	})
 }
@@ -3,6 +3,7 @@ package dotnetclient
 import (
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/apex/rpc/internal/format"
 	"github.com/apex/rpc/schema"
@@ -10,6 +11,7 @@ import (
 
 var namespace = `using System;
 using System.Collections.Generic;
+using System.Linq;
 using System.Net.Http;
 using System.Threading.Tasks;
 using Newtonsoft.Json;
@@ -18,14 +20,38 @@ namespace %s
 {
 	public class %s
 	{
-		class ApexLogsException : Exception
+		public class ApexLogsException : Exception
 		{
+			public int Status { get; }
+			public string Type { get; }
+			public string RequestId { get; internal set; }
+
 			public ApexLogsException(int status) : base($"{status} response") 
-			{ }
+			{
+				Status = status;
+			}
 
 			public ApexLogsException(int status, string type, string message) 
 				: base($"{status} response: ${type}: {message}") 
-			{ }
+			{
+				Status = status;
+				Type = type;
+			}
+		}
+
+		class ErrorResponse
+		{
+			[JsonProperty("type")]
+			public string Type { get; set; }
+
+			[JsonProperty("message")]
+			public string Message { get; set; }
+
+			[JsonProperty("details")]
+			public Dictionary<string, object> Details { get; set; }
+
+			[JsonProperty("request_id")]
+			public string RequestId { get; set; }
 		}
 
 		private readonly string _url;
@@ -62,10 +88,20 @@ var call = `
 
 			if (statusCode < 300) return content;
 
-			var body = JsonConvert.DeserializeObject<Dictionary<string, string>>(content)
-				?? throw new ApexLogsException(statusCode);
+			var requestId = response.Headers.TryGetValues("X-Request-Id", out var values)
+				? values.FirstOrDefault()
+				: null;
+
+			var body = JsonConvert.DeserializeObject<ErrorResponse>(content)
+				?? throw new ApexLogsException(statusCode) { RequestId = requestId };
 
-			throw new ApexLogsException(statusCode, body["type"], body["message"]);
+			requestId = body.RequestId ?? requestId;
+
+			switch (body.Type)
+			{
+%s				default:
+					throw new ApexLogsException(statusCode, body.Type, body.Message) { RequestId = requestId };
+			}
 		}
 `
 
@@ -79,13 +115,38 @@ func Generate(w io.Writer, s *schema.Schema, namespaceName, className string) er
 
 	out(w, namespace, namespaceName, className)
 
+	// errors
+	for _, e := range s.ErrorsSlice() {
+		name := format.GoName(e.Name) + "Exception"
+		out(w, "\n")
+		out(w, "\t\t/// %s %s\n", name, e.Description)
+		out(w, "\t\tpublic class %s : ApexLogsException\n", name)
+		out(w, "\t\t{\n")
+		out(w, "\t\t\tpublic Dictionary<string, object> Details { get; }\n")
+		out(w, "\n")
+		out(w, "\t\t\tpublic %s(int status, string message, Dictionary<string, object> details)\n", name)
+		out(w, "\t\t\t\t: base(status, %q, message)\n", e.Name)
+		out(w, "\t\t\t{\n")
+		out(w, "\t\t\t\tDetails = details;\n")
+		out(w, "\t\t\t}\n")
+		out(w, "\t\t}\n")
+	}
+
 	var indentDeclaration = "		"
 	var indentContent = "			"
 	for _, m := range s.Methods {
+		// streaming methods are not supported by this client
+		if m.Stream {
+			continue
+		}
+
 		var name = format.GoName(m.Name)
 		// comment
 		out(w, "\n")
 		out(w, "%s/// %s\n", indentDeclaration, m.Description)
+		if len(m.Scopes) > 0 {
+			out(w, "%s/// %s\n", indentDeclaration, format.Scopes(m.Scopes))
+		}
 
 		///TODO: add parameters description
 
@@ -128,7 +189,13 @@ func Generate(w io.Writer, s *schema.Schema, namespaceName, className string) er
 		out(w, "%s}\n", indentDeclaration)
 	}
 
-	out(w, call)
+	var cases strings.Builder
+	for _, e := range s.ErrorsSlice() {
+		out(&cases, "\t\t\t\tcase %q:\n", e.Name)
+		out(&cases, "\t\t\t\t\tthrow new %sException(statusCode, body.Message, body.Details) { RequestId = requestId };\n", format.GoName(e.Name))
+	}
+
+	out(w, call, cases.String())
 	out(w, closeNamespace)
 
 	return nil
@@ -1,5 +1,6 @@
 using System;
 using System.Collections.Generic;
+using System.Linq;
 using System.Net.Http;
 using System.Threading.Tasks;
 using Newtonsoft.Json;
@@ -8,14 +9,38 @@ namespace ApexLogs
 {
 	public class Client
 	{
-		class ApexLogsException : Exception
+		public class ApexLogsException : Exception
 		{
+			public int Status { get; }
+			public string Type { get; }
+			public string RequestId { get; internal set; }
+
 			public ApexLogsException(int status) : base($"{status} response") 
-			{ }
+			{
+				Status = status;
+			}
 
 			public ApexLogsException(int status, string type, string message) 
 				: base($"{status} response: ${type}: {message}") 
-			{ }
+			{
+				Status = status;
+				Type = type;
+			}
+		}
+
+		class ErrorResponse
+		{
+			[JsonProperty("type")]
+			public string Type { get; set; }
+
+			[JsonProperty("message")]
+			public string Message { get; set; }
+
+			[JsonProperty("details")]
+			public Dictionary<string, object> Details { get; set; }
+
+			[JsonProperty("request_id")]
+			public string RequestId { get; set; }
 		}
 
 		private readonly string _url;
@@ -29,6 +54,18 @@ namespace ApexLogs
 			_authToken = authToken;
 		}
 
+		/// ItemNotFoundException is returned when the item does not exist.
+		public class ItemNotFoundException : ApexLogsException
+		{
+			public Dictionary<string, object> Details { get; }
+
+			public ItemNotFoundException(int status, string message, Dictionary<string, object> details)
+				: base(status, "item_not_found", message)
+			{
+				Details = details;
+			}
+		}
+
 		/// adds an item to the list.
 		public async Task AddItem(AddItemInput parameter)
 		{
@@ -44,6 +81,7 @@ namespace ApexLogs
 		}
 
 		/// removes an item from the to-do list.
+		/// Requires the items:write scope.
 		public async Task<RemoveItemOutput> RemoveItem(RemoveItemInput parameter)
 		{
 			var res = await Call("remove_item", parameter);
@@ -72,10 +110,22 @@ namespace ApexLogs
 
 			if (statusCode < 300) return content;
 
-			var body = JsonConvert.DeserializeObject<Dictionary<string, string>>(content)
-				?? throw new ApexLogsException(statusCode);
+			var requestId = response.Headers.TryGetValues("X-Request-Id", out var values)
+				? values.FirstOrDefault()
+				: null;
+
+			var body = JsonConvert.DeserializeObject<ErrorResponse>(content)
+				?? throw new ApexLogsException(statusCode) { RequestId = requestId };
 
-			throw new ApexLogsException(statusCode, body["type"], body["message"]);
+			requestId = body.RequestId ?? requestId;
+
+			switch (body.Type)
+			{
+				case "item_not_found":
+					throw new ItemNotFoundException(statusCode, body.Message, body.Details) { RequestId = requestId };
+				default:
+					throw new ApexLogsException(statusCode, body.Type, body.Message) { RequestId = requestId };
+			}
 		}
 	}
 }
@@ -68,6 +68,11 @@ func generateMethodFuncs(w io.Writer, s *schema.Schema) {
 	out := fmt.Fprintf
 	out(w, "-- METHODS\n\n")
 	for _, m := range s.Methods {
+		// streaming methods are not supported by this client
+		if m.Stream {
+			continue
+		}
+
 		name := format.JsName(m.Name)
 		out(w, "%s : %sInput \n", name, format.GoName(m.Name))
 		out(w, "%s = \n   ...", name)
@@ -32,6 +32,11 @@ type alias RemoveItemOutput =
   { item : Item
   }
 
+{-| WatchItemsOutput params. -}
+type alias WatchItemsOutput =
+  { item : Item
+  }
+
 -- METHODS
 
 addItem : AddItemInput 
@@ -80,3 +85,9 @@ removeItemOutputDecoder =
       |> required "item" itemDecoder
 
 
+watchItemsOutputDecoder : Decoder WatchItemsOutput
+watchItemsOutputDecoder =
+    Decode.success WatchItemsOutput
+      |> required "item" itemDecoder
+
+
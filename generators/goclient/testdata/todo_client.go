@@ -8,32 +8,165 @@ type Client struct {
 
   // HTTPClient is the client used for making requests, defaulting to http.DefaultClient.
   HTTPClient *http.Client
+
+  // Codec is the codec used for encoding requests and decoding responses, defaulting to JSON.
+  Codec Codec
+
+  // MaxRetries is the number of times calls are retried after rate limited responses,
+  // and calls of idempotent and safe methods after network errors or retryable responses,
+  // reusing their Idempotency-Key. Retries wait for the Retry-After hint when provided.
+  MaxRetries int
 }
 
 // AddItem adds an item to the list.
-func (c *Client) AddItem(in AddItemInput) error {
-  return call(c.HTTPClient, c.AuthToken, c.URL, "add_item", in, nil)
+func (c *Client) AddItem(ctx context.Context, in AddItemInput) error {
+  return c.callIdempotent(ctx, "add_item", in, nil)
 }
 
 // GetItems returns all items in the list.
-func (c *Client) GetItems() (*GetItemsOutput, error) {
+func (c *Client) GetItems(ctx context.Context) (*GetItemsOutput, error) {
   var out GetItemsOutput
-  return &out, call(c.HTTPClient, c.AuthToken, c.URL, "get_items", nil, &out)
+  return &out, c.callSafe(ctx, "get_items", nil, &out)
 }
 
 // RemoveItem removes an item from the to-do list.
-func (c *Client) RemoveItem(in RemoveItemInput) (*RemoveItemOutput, error) {
+//
+// Requires the items:write scope.
+func (c *Client) RemoveItem(ctx context.Context, in RemoveItemInput) (*RemoveItemOutput, error) {
   var out RemoveItemOutput
-  return &out, call(c.HTTPClient, c.AuthToken, c.URL, "remove_item", in, &out)
+  return &out, c.call(ctx, "remove_item", in, &out)
+}
+
+// WatchItemsStream is a stream of WatchItemsOutput values.
+type WatchItemsStream struct {
+  *stream
+  value WatchItemsOutput
+}
+
+// Next advances to the next value, returning false at the end of the stream or on error.
+func (s *WatchItemsStream) Next() bool {
+  s.value = WatchItemsOutput{}
+  return s.next(&s.value)
+}
+
+// Value returns the current value.
+func (s *WatchItemsStream) Value() *WatchItemsOutput {
+  return &s.value
+}
+
+// WatchItems streams items as they are added to the list. The stream must be closed.
+func (c *Client) WatchItems(ctx context.Context) (*WatchItemsStream, error) {
+  s, err := c.openStream(ctx, "watch_items", nil)
+  if err != nil {
+    return nil, err
+  }
+  return &WatchItemsStream{stream: s}, nil
+}
+
+// AddItemCall is a batched call of the add_item method.
+type AddItemCall struct {
+  *batchCall
+}
+
+// Err returns the error of the call once the batch is sent.
+func (c *AddItemCall) Err() error {
+  return c.err
 }
 
+// AddItem adds a call of the add_item method to the batch.
+func (b *Batch) AddItem(in AddItemInput) *AddItemCall {
+  c := &AddItemCall{}
+  c.batchCall = b.add("add_item", in, nil)
+  return c
+}
+
+// GetItemsCall is a batched call of the get_items method.
+type GetItemsCall struct {
+  *batchCall
+  out GetItemsOutput
+}
+
+// Result returns the output of the call once the batch is sent.
+func (c *GetItemsCall) Result() (*GetItemsOutput, error) {
+  return &c.out, c.err
+}
+
+// GetItems adds a call of the get_items method to the batch.
+func (b *Batch) GetItems() *GetItemsCall {
+  c := &GetItemsCall{}
+  c.batchCall = b.add("get_items", nil, &c.out)
+  return c
+}
+
+// RemoveItemCall is a batched call of the remove_item method.
+type RemoveItemCall struct {
+  *batchCall
+  out RemoveItemOutput
+}
+
+// Result returns the output of the call once the batch is sent.
+func (c *RemoveItemCall) Result() (*RemoveItemOutput, error) {
+  return &c.out, c.err
+}
+
+// RemoveItem adds a call of the remove_item method to the batch.
+//
+// Requires the items:write scope.
+func (b *Batch) RemoveItem(in RemoveItemInput) *RemoveItemCall {
+  c := &RemoveItemCall{}
+  c.batchCall = b.add("remove_item", in, &c.out)
+  return c
+}
+
+
+// Codec is the interface used for encoding requests and decoding responses.
+type Codec interface {
+	ContentType() string
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+// compressionThreshold is the minimum size in bytes of a request body before it is compressed.
+const compressionThreshold = 1024
+
+// jsonCodec is the default JSON codec.
+type jsonCodec struct{}
+
+// ContentType implementation.
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+// Encode implementation.
+func (jsonCodec) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// Decode implementation.
+func (jsonCodec) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// FieldError is a field validation error, where Path is a JSON pointer to the field.
+type FieldError struct {
+	Path    string `json:"path"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
 
 // Error is an error returned by the client.
 type Error struct {
-	Status     string
-	StatusCode int
-	Type       string
-	Message    string
+	Status     string       `json:"-"`
+	StatusCode int          `json:"-"`
+	Type       string       `json:"type"`
+	Message    string       `json:"message"`
+	Fields     []FieldError `json:"fields"`
+	Retryable  bool         `json:"retryable"`
+	RetryAfter int          `json:"retry_after"`
+	RequestID  string       `json:"request_id"`
+
+	// err is the typed error declared in the schema, if any.
+	err error
 }
 
 // Error implementation.
@@ -44,64 +177,518 @@ func (e Error) Error() string {
 	return fmt.Sprintf("%s: %s", e.Type, e.Message)
 }
 
-// call implementation.
-func call(client *http.Client, authToken, endpoint, method string, in, out interface{}) error {
+// Unwrap returns the typed error declared in the schema, if any.
+func (e Error) Unwrap() error {
+	return e.err
+}
+
+// call calls method with in, decoding the output into out, retrying up to
+// MaxRetries times after rate limited responses.
+func (c *Client) call(ctx context.Context, method string, in, out interface{}) error {
+	return c.retry(ctx, rateLimited, func() error {
+		return c.do(ctx, method, nil, in, out)
+	})
+}
+
+// callIdempotent calls an idempotent method with an Idempotency-Key, retrying up to
+// MaxRetries times with the same key after network errors and retryable responses.
+func (c *Client) callIdempotent(ctx context.Context, method string, in, out interface{}) error {
+	header := http.Header{}
+	header.Set("Idempotency-Key", newKey())
+
+	return c.retry(ctx, retryable, func() error {
+		return c.do(ctx, method, header, in, out)
+	})
+}
+
+// callSafe calls a safe method with a GET request, sending in as query-string parameters,
+// retrying up to MaxRetries times after network errors and retryable responses.
+func (c *Client) callSafe(ctx context.Context, method string, in, out interface{}) error {
+	query, err := queryValues(in)
+	if err != nil {
+		return fmt.Errorf("encoding: %w", err)
+	}
+
+	return c.retry(ctx, retryable, func() error {
+		return c.do(ctx, method, nil, query, out)
+	})
+}
+
+// queryValues returns the query-string parameters of in, where arrays are repeated
+// parameters and objects are JSON encoded.
+func queryValues(in interface{}) (url.Values, error) {
+	query := url.Values{}
+	if in == nil {
+		return query, nil
+	}
+
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, raw := range fields {
+		switch raw[0] {
+		case 'n':
+			// null
+		case '[':
+			var items []json.RawMessage
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return nil, err
+			}
+			for _, item := range items {
+				query.Add(name, queryValue(item))
+			}
+		default:
+			query.Set(name, queryValue(raw))
+		}
+	}
+
+	return query, nil
+}
+
+// queryValue returns the query-string value of the JSON value raw.
+func queryValue(raw json.RawMessage) string {
+	var s string
+	if raw[0] == '"' && json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// retry calls fn, retrying up to MaxRetries times with backoff while retry returns true for its error.
+func (c *Client) retry(ctx context.Context, retry func(error) bool, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= c.MaxRetries || !retry(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff(attempt, err)):
+		}
+	}
+}
+
+// do calls method with in and the given header, decoding the output into out.
+func (c *Client) do(ctx context.Context, method string, header http.Header, in, out interface{}) error {
+	codec := c.codec()
+
+	body, err := c.send(ctx, codec, method, codec.ContentType(), header, in)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	// output params
+	if out != nil {
+		err = codec.Decode(body, out)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// codec returns the codec of the client, defaulting to JSON.
+func (c *Client) codec() Codec {
+	if c.Codec == nil {
+		return jsonCodec{}
+	}
+	return c.Codec
+}
+
+// send sends a request encoded with codec accepting the given media type, returning the decompressed response body.
+// Inputs of url.Values are sent as the query-string of a GET request. The deadline of ctx, if any, is sent in the Rpc-Timeout header so the server stops work the client no longer awaits.
+func (c *Client) send(ctx context.Context, codec Codec, method, accept string, header http.Header, in interface{}) (io.ReadCloser, error) {
 	var body io.Reader
 
 	// default client
+	client := c.HTTPClient
 	if client == nil {
 		client = http.DefaultClient
 	}
 
 	// input params
-	if in != nil {
+	var compressed bool
+	query, get := in.(url.Values)
+	if in != nil && !get {
 		var buf bytes.Buffer
-		err := json.NewEncoder(&buf).Encode(in)
+		err := codec.Encode(&buf, in)
 		if err != nil {
-			return fmt.Errorf("encoding: %w", err)
+			return nil, fmt.Errorf("encoding: %w", err)
 		}
 		body = &buf
+
+		// compress large bodies
+		if buf.Len() >= compressionThreshold {
+			var zbuf bytes.Buffer
+			zw := gzip.NewWriter(&zbuf)
+			_, err = zw.Write(buf.Bytes())
+			if err == nil {
+				err = zw.Close()
+			}
+			if err != nil {
+				return nil, fmt.Errorf("compressing: %w", err)
+			}
+			body = &zbuf
+			compressed = true
+		}
 	}
 
-	// POST request
-	req, err := http.NewRequest("POST", endpoint+"/"+method, body)
+	// request
+	var req *http.Request
+	var err error
+	if get {
+		u := c.URL + "/" + method
+		if len(query) > 0 {
+			u += "?" + query.Encode()
+		}
+		req, err = http.NewRequestWithContext(ctx, "GET", u, nil)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, "POST", c.URL+"/"+method, body)
+	}
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if !get {
+		req.Header.Set("Content-Type", codec.ContentType())
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	// trace context
+	traceparent, _ := ctx.Value(traceparentKey{}).(string)
+	if traceparent == "" {
+		traceparent = "00-" + randomHex(16) + "-" + randomHex(8) + "-01"
+	}
+	req.Header.Set("traceparent", traceparent)
+
+	// deadline
+	if d, ok := ctx.Deadline(); ok {
+		ms := time.Until(d).Milliseconds()
+		if ms < 0 {
+			ms = 0
+		}
+		req.Header.Set("Rpc-Timeout", strconv.FormatInt(ms, 10))
 	}
-	req.Header.Set("Content-Type", "application/json")
 
 	// auth token
-	if authToken != "" {
-		req.Header.Set("Authorization", "Bearer "+authToken)
+	if c.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.AuthToken)
 	}
 
 	// response
 	res, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// decompress
+	var resBody io.ReadCloser = res.Body
+	if res.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(res.Body)
+		if err != nil {
+			res.Body.Close()
+			return nil, fmt.Errorf("decompressing: %w", err)
+		}
+		resBody = readCloser{zr, res.Body}
 	}
-	defer res.Body.Close()
 
 	// error
 	if res.StatusCode >= 300 {
+		defer resBody.Close()
 		var e Error
-		if res.Header.Get("Content-Type") == "application/json" {
-			if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
-				return err
+		if res.Header.Get("Content-Type") == codec.ContentType() {
+			var buf bytes.Buffer
+			if _, err := buf.ReadFrom(resBody); err != nil {
+				return nil, err
 			}
+			if err := codec.Decode(bytes.NewReader(buf.Bytes()), &e); err != nil {
+				return nil, err
+			}
+			e.err = decodeError(codec, e.Type, buf.Bytes())
+		}
+		if e.RequestID == "" {
+			e.RequestID = res.Header.Get("X-Request-Id")
+		}
+		if e.RetryAfter == 0 {
+			e.RetryAfter, _ = strconv.Atoi(res.Header.Get("Retry-After"))
 		}
 		e.Status = http.StatusText(res.StatusCode)
 		e.StatusCode = res.StatusCode
-		return e
+		return nil, e
 	}
 
-	// output params
-	if out != nil {
-		err = json.NewDecoder(res.Body).Decode(out)
+	return resBody, nil
+}
+
+// traceparentKey is the context key of the traceparent.
+type traceparentKey struct{}
+
+// WithTraceparent returns a copy of ctx with the W3C traceparent sent with calls,
+// such as the value of rpc.TraceparentFromContext within a method. Calls start a
+// new trace when ctx has none.
+func WithTraceparent(ctx context.Context, traceparent string) context.Context {
+	return context.WithValue(ctx, traceparentKey{}, traceparent)
+}
+
+// newKey returns a new random idempotency key.
+func newKey() string {
+	return randomHex(16)
+}
+
+// randomHex returns n random bytes, hex encoded.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// retryable returns true if err is a network error or a retryable response.
+func retryable(err error) bool {
+	var e Error
+	if errors.As(err, &e) {
+		switch e.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		default:
+			return e.Retryable
+		}
+	}
+
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
+
+// rateLimited returns true if err is a rate limited response, which is safe to retry as the call was rejected.
+func rateLimited(err error) bool {
+	var e Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusTooManyRequests
+}
+
+// backoff returns the delay before a retry, using the Retry-After hint of err when provided.
+func backoff(attempt int, err error) time.Duration {
+	var e Error
+	if errors.As(err, &e) && e.RetryAfter > 0 {
+		return time.Duration(e.RetryAfter) * time.Second
+	}
+	return (100 * time.Millisecond) << uint(attempt)
+}
+
+// readCloser is a reader closed by its closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// stream is a stream of newline-delimited JSON frames.
+type stream struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	err    error
+	done   bool
+}
+
+// frame is a stream frame holding a value, an error, or the end of the stream.
+type frame struct {
+	Data  json.RawMessage `json:"data"`
+	Error json.RawMessage `json:"error"`
+	End   bool            `json:"end"`
+}
+
+// openStream calls a streaming method, returning a stream of its outputs.
+func (c *Client) openStream(ctx context.Context, method string, in interface{}) (*stream, error) {
+	body, err := c.send(ctx, c.codec(), method, "application/x-ndjson", nil, in)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stream{body: body, reader: bufio.NewReader(body)}, nil
+}
+
+// next decodes the next value into out, returning false at the end of the stream or on error.
+// A stream closed without an end or error frame is truncated and ends with io.ErrUnexpectedEOF.
+func (s *stream) next(out interface{}) bool {
+	for !s.done {
+		line, err := s.reader.ReadBytes('\n')
 		if err != nil {
-			return err
+			s.done = true
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			s.err = err
+			return false
+		}
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var f frame
+		if err := json.Unmarshal(line, &f); err != nil {
+			s.err = fmt.Errorf("decoding: %w", err)
+			s.done = true
+			return false
+		}
+
+		if f.Error != nil {
+			var e Error
+			if err := json.Unmarshal(f.Error, &e); err != nil {
+				s.err = fmt.Errorf("decoding: %w", err)
+			} else {
+				e.err = decodeError(jsonCodec{}, e.Type, f.Error)
+				s.err = e
+			}
+			s.done = true
+			return false
+		}
+
+		if f.End {
+			s.done = true
+			return false
+		}
+
+		if err := json.Unmarshal(f.Data, out); err != nil {
+			s.err = fmt.Errorf("decoding: %w", err)
+			s.done = true
+			return false
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// Err returns the error which ended the stream, if any.
+func (s *stream) Err() error {
+	return s.err
+}
+
+// Close closes the stream, canceling the method if it has not finished.
+func (s *stream) Close() error {
+	return s.body.Close()
+}
+
+// errNotSent is the error of calls in a batch which has not been sent.
+var errNotSent = fmt.Errorf("batch not sent")
+
+// Batch is a batch of calls sent in a single request, which is always encoded as JSON.
+type Batch struct {
+	client *Client
+	calls  []*batchCall
+}
+
+// batchCall is a call in a batch.
+type batchCall struct {
+	Method string      `json:"method"`
+	Input  interface{} `json:"input,omitempty"`
+
+	out interface{}
+	err error
+}
+
+// batchResult is the result of a call in a batch.
+type batchResult struct {
+	Status int             `json:"status"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// NewBatch returns a new batch of calls.
+func (c *Client) NewBatch() *Batch {
+	return &Batch{client: c}
+}
+
+// add adds a call of method to the batch.
+func (b *Batch) add(method string, in, out interface{}) *batchCall {
+	c := &batchCall{Method: method, Input: in, out: out, err: errNotSent}
+	b.calls = append(b.calls, c)
+	return c
+}
+
+// Send sends the batch, returning an error when the request fails. The
+// results of individual calls are returned by the calls.
+func (b *Batch) Send(ctx context.Context) error {
+	err := b.send(ctx)
+	if err != nil {
+		for _, c := range b.calls {
+			c.err = err
+		}
+	}
+	return err
+}
+
+// send implementation.
+func (b *Batch) send(ctx context.Context) error {
+	body, err := b.client.send(ctx, jsonCodec{}, "_batch", "application/json", nil, b.calls)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var results []batchResult
+	err = json.NewDecoder(body).Decode(&results)
+	if err != nil {
+		return fmt.Errorf("decoding: %w", err)
+	}
+
+	if len(results) != len(b.calls) {
+		return fmt.Errorf("batch of %d calls returned %d results", len(b.calls), len(results))
+	}
+
+	for i, r := range results {
+		call := b.calls[i]
+		call.err = nil
+
+		if r.Error != nil {
+			var e Error
+			if err := json.Unmarshal(r.Error, &e); err != nil {
+				call.err = fmt.Errorf("decoding: %w", err)
+				continue
+			}
+			e.err = decodeError(jsonCodec{}, e.Type, r.Error)
+			e.Status = http.StatusText(r.Status)
+			e.StatusCode = r.Status
+			call.err = e
+			continue
+		}
+
+		if call.out != nil && r.Result != nil {
+			if err := json.Unmarshal(r.Result, call.out); err != nil {
+				call.err = fmt.Errorf("decoding: %w", err)
+			}
 		}
 	}
 
 	return nil
 }
+
+// decodeError returns the typed error declared in the schema for kind, or nil.
+func decodeError(codec Codec, kind string, body []byte) error {
+  switch kind {
+  case "item_not_found":
+    var e ItemNotFoundError
+    if codec.Decode(bytes.NewReader(body), &e) == nil {
+      return e
+    }
+  }
+  return nil
+}
@@ -3,19 +3,44 @@ package goserver
 import (
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/apex/rpc/internal/format"
 	"github.com/apex/rpc/schema"
 )
 
-// Generate writes the Go server implementations to w.
-func Generate(w io.Writer, s *schema.Schema, tracing bool, types string) error {
+// Generate writes the Go server implementations to w, with a /_batch route when batch is true.
+func Generate(w io.Writer, s *schema.Schema, tracing bool, types string, batch bool) error {
 	// router
-	err := writeRouter(w, s, types)
+	err := writeRouter(w, s, types, batch)
 	if err != nil {
 		return fmt.Errorf("writing router: %w", err)
 	}
 
+	// dispatcher
+	err = writeDispatcher(w, s, types)
+	if err != nil {
+		return fmt.Errorf("writing dispatcher: %w", err)
+	}
+
+	// metrics
+	err = writeMetrics(w, s)
+	if err != nil {
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+
+	// authenticator
+	err = writeAuthenticator(w, s, batch)
+	if err != nil {
+		return fmt.Errorf("writing authenticator: %w", err)
+	}
+
+	// stream senders
+	err = writeSenders(w, s, types)
+	if err != nil {
+		return fmt.Errorf("writing senders: %w", err)
+	}
+
 	// method stubs
 	err = writeMethods(w, s, tracing, types)
 	if err != nil {
@@ -26,40 +51,143 @@ func Generate(w io.Writer, s *schema.Schema, tracing bool, types string) error {
 }
 
 // writeRouter writes the routing implementation to w.
-func writeRouter(w io.Writer, s *schema.Schema, types string) error {
+func writeRouter(w io.Writer, s *schema.Schema, types string, batch bool) error {
 	out := fmt.Fprintf
 	out(w, "// ServeHTTP implementation.\n")
 	out(w, "func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {\n")
+	out(w, "  w = rpc.Negotiate(w, r)\n\n")
+	out(w, "  if rpc.HandleCORS(w, r, s) {\n")
+	out(w, "    return\n")
+	out(w, "  }\n\n")
+
+	// safe methods are also served by GET requests
+	var hasSafe bool
+	for _, m := range s.Methods {
+		hasSafe = hasSafe || safe(m)
+	}
+
+	if hasSafe {
+		out(w, "  var cacheControl string\n\n")
+	}
+
 	out(w, "  if r.Method == \"GET\" {\n")
 	out(w, "    switch r.URL.Path {\n")
 	out(w, "      case \"/_health\":\n")
 	out(w, "        rpc.WriteHealth(w, s)\n")
+	out(w, "        return\n")
+	out(w, "      case \"/_health/live\":\n")
+	out(w, "        rpc.WriteHealthReport(w, r, s, rpc.Liveness, %q, %q)\n", s.Name, s.Version)
+	out(w, "        return\n")
+	out(w, "      case \"/_health/ready\":\n")
+	out(w, "        rpc.WriteHealthReport(w, r, s, rpc.Readiness, %q, %q)\n", s.Name, s.Version)
+	out(w, "        return\n")
+	out(w, "      case \"/_metrics\":\n")
+	out(w, "        if rpc.ServeMetrics(w, r, s) {\n")
+	out(w, "          return\n")
+	out(w, "        }\n")
+	out(w, "        rpc.WriteError(w, rpc.BadRequest(\"Invalid method\"))\n")
+	out(w, "        return\n")
+	var unsafe []string
+	if batch {
+		unsafe = append(unsafe, fmt.Sprintf("%q", "/_batch"))
+	}
+	for _, m := range s.Methods {
+		if safe(m) {
+			out(w, "      case \"/%s\":\n", m.Name)
+			out(w, "        cacheControl = %q\n", cacheControl(m))
+		} else {
+			unsafe = append(unsafe, fmt.Sprintf("%q", "/"+m.Name))
+		}
+	}
+	if len(unsafe) > 0 {
+		out(w, "      case %s:\n", strings.Join(unsafe, ", "))
+		out(w, "        rpc.WriteError(w, rpc.MethodNotAllowed(\"Method not allowed\", \"POST\", \"OPTIONS\"))\n")
+		out(w, "        return\n")
+	}
 	out(w, "      default:\n")
 	out(w, "        rpc.WriteError(w, rpc.BadRequest(\"Invalid method\"))\n")
+	out(w, "        return\n")
 	out(w, "    }\n")
-	out(w, "    return\n")
 	out(w, "  }\n\n")
-	out(w, "  if r.Method == \"POST\" {\n")
-	out(w, "    ctx := rpc.NewRequestContext(r.Context(), r)\n")
+
+	if hasSafe {
+		out(w, "  if r.Method == \"POST\" || r.Method == \"GET\" {\n")
+	} else {
+		out(w, "  if r.Method == \"POST\" {\n")
+	}
+	out(w, "    ctx, cancel := rpc.WithTimeout(r.Context(), r)\n")
+	out(w, "    defer cancel()\n")
+	out(w, "    r = r.WithContext(ctx)\n")
+	out(w, "    ctx = rpc.NewRequestContext(ctx, r)\n")
+	out(w, "    ctx = rpc.NewTraceContext(ctx, r)\n")
+	out(w, "    ctx, err := s.authenticate(ctx, r)\n")
+	out(w, "    if err != nil {\n")
+	out(w, "      rpc.WriteError(w, err)\n")
+	out(w, "      return\n")
+	out(w, "    }\n")
+	out(w, "    r = r.WithContext(ctx)\n")
 	out(w, "    var res interface{}\n")
-	out(w, "    var err error\n")
 	out(w, "    switch r.URL.Path {\n")
+	if batch {
+		out(w, "      case \"/_batch\":\n")
+		out(w, "        rpc.ServeBatch(w, r, s.callBatched)\n")
+		out(w, "        return\n")
+	}
 	for _, m := range s.Methods {
+		// idempotent
+		if m.Idempotent && !m.Stream {
+			out(w, "      case \"/%s\":\n", m.Name)
+			out(w, "        rpc.ServeIdempotent(w, r, s, func() (interface{}, error) {\n")
+			out(w, "          return s.callMethod(ctx, r.URL.Path, r)\n")
+			out(w, "        }%s)\n", readOptions(m))
+			out(w, "        return\n")
+			continue
+		}
+
+		// other methods are invoked by callMethod
+		if !m.Stream {
+			continue
+		}
+
+		args := "ctx, "
+		in := "nil"
 		out(w, "      case \"/%s\":\n", m.Name)
-		// parse input
+		out(w, "        done := rpc.BeginMetrics(s, r.URL.Path)\n")
+		out(w, "        defer func() {\n")
+		out(w, "          done(err)\n")
+		out(w, "        }()\n")
+		if len(m.Scopes) > 0 {
+			out(w, "        err = rpc.Authorize(ctx, %s)\n", scopes(m))
+			out(w, "        if err != nil {\n")
+			out(w, "          break\n")
+			out(w, "        }\n")
+		}
+		if throttled(m) {
+			out(w, "        var release func()\n")
+			out(w, "        release, err = rpc.Throttle(ctx, s, %q, %s)\n", m.Name, rateLimit(m))
+			out(w, "        if err != nil {\n")
+			out(w, "          break\n")
+			out(w, "        }\n")
+			out(w, "        defer release()\n")
+		}
 		if len(m.Inputs) > 0 {
+			args += fmt.Sprintf("in.(%s), ", format.GoInputType(types, m.Name))
+			in = "in"
 			out(w, "        var in %s\n", format.GoInputType(types, m.Name))
-			out(w, "        err = rpc.ReadRequest(r, &in)\n")
+			out(w, "        err = rpc.ReadRequest(r, &in%s)\n", readOptions(m))
 			out(w, "        if err != nil {\n")
 			out(w, "          break\n")
 			out(w, "        }\n")
-			out(w, "        res, err = s.%s(ctx, in)\n", format.JsName(m.Name))
-		} else {
-			out(w, "        res, err = s.%s(ctx)\n", format.JsName(m.Name))
 		}
+		out(w, "        stream := rpc.NewStream(w, r)\n")
+		out(w, "        _, err = rpc.Intercept(ctx, s, %s, %s, func(ctx context.Context, in interface{}) (interface{}, error) {\n", methodInfo(m), in)
+		out(w, "          return nil, s.%s(%s%sSender{stream})\n", format.JsName(m.Name), args, format.GoName(m.Name))
+		out(w, "        })\n")
+		out(w, "        stream.Finish(err)\n")
+		out(w, "        return\n")
 	}
 	out(w, "      default:\n")
-	out(w, "        err = rpc.BadRequest(\"Invalid method\")\n")
+	out(w, "        res, err = s.callMethod(ctx, r.URL.Path, r)\n")
 	out(w, "    }\n")
 	out(w, "\n")
 	out(w, "    if err != nil {\n")
@@ -67,13 +195,285 @@ func writeRouter(w io.Writer, s *schema.Schema, types string) error {
 	out(w, "      return\n")
 	out(w, "    }\n")
 	out(w, "\n")
+	if hasSafe {
+		out(w, "    if r.Method == \"GET\" {\n")
+		out(w, "      rpc.WriteCacheable(w, r, res, cacheControl)\n")
+		out(w, "      return\n")
+		out(w, "    }\n")
+		out(w, "\n")
+	}
 	out(w, "    rpc.WriteResponse(w, res)\n")
 	out(w, "    return\n")
+	out(w, "  }\n\n")
+	out(w, "  if r.Method == \"OPTIONS\" {\n")
+	out(w, "    w.Header().Set(\"Allow\", \"GET, POST, OPTIONS\")\n")
+	out(w, "    w.WriteHeader(http.StatusNoContent)\n")
+	out(w, "    return\n")
+	out(w, "  }\n\n")
+	out(w, "  rpc.WriteError(w, rpc.MethodNotAllowed(\"Method not allowed\", \"GET\", \"POST\", \"OPTIONS\"))\n")
+	out(w, "}\n")
+	return nil
+}
+
+// writeDispatcher writes the invocation of methods by path to w.
+func writeDispatcher(w io.Writer, s *schema.Schema, types string) error {
+	out := fmt.Fprintf
+	out(w, "\n")
+	out(w, "// callMethod invokes the method at path with the input read from r.\n")
+	out(w, "func (s *Server) callMethod(ctx context.Context, path string, r *http.Request) (res interface{}, err error) {\n")
+	out(w, "  done := rpc.BeginMetrics(s, path)\n")
+	out(w, "  defer func() {\n")
+	out(w, "    done(err)\n")
+	out(w, "  }()\n\n")
+	out(w, "  switch path {\n")
+	for _, m := range s.Methods {
+		out(w, "    case \"/%s\":\n", m.Name)
+
+		// streams are routed by ServeHTTP
+		if m.Stream {
+			out(w, "      return nil, rpc.BadRequest(\"Streaming methods must not be batched\")\n")
+			continue
+		}
+
+		if len(m.Scopes) > 0 {
+			out(w, "      if err := rpc.Authorize(ctx, %s); err != nil {\n", scopes(m))
+			out(w, "        return nil, err\n")
+			out(w, "      }\n")
+		}
+
+		assign := ":="
+		if throttled(m) {
+			assign = "="
+			out(w, "      release, err := rpc.Throttle(ctx, s, %q, %s)\n", m.Name, rateLimit(m))
+			out(w, "      if err != nil {\n")
+			out(w, "        return nil, err\n")
+			out(w, "      }\n")
+			out(w, "      defer release()\n")
+		}
+
+		if len(m.Inputs) > 0 {
+			out(w, "      var in %s\n", format.GoInputType(types, m.Name))
+			if safe(m) {
+				out(w, "      read := rpc.ReadRequest\n")
+				out(w, "      if r.Method == \"GET\" {\n")
+				out(w, "        read = rpc.ReadQuery\n")
+				out(w, "      }\n")
+				out(w, "      err %s read(r, &in%s)\n", assign, readOptions(m))
+			} else {
+				out(w, "      err %s rpc.ReadRequest(r, &in%s)\n", assign, readOptions(m))
+			}
+			out(w, "      if err != nil {\n")
+			out(w, "        return nil, err\n")
+			out(w, "      }\n")
+			out(w, "      return rpc.Intercept(ctx, s, %s, in, func(ctx context.Context, in interface{}) (interface{}, error) {\n", methodInfo(m))
+			out(w, "        return s.%s(ctx, in.(%s))\n", format.JsName(m.Name), format.GoInputType(types, m.Name))
+			out(w, "      })\n")
+		} else {
+			out(w, "      return rpc.Intercept(ctx, s, %s, nil, func(ctx context.Context, in interface{}) (interface{}, error) {\n", methodInfo(m))
+			out(w, "        return s.%s(ctx)\n", format.JsName(m.Name))
+			out(w, "      })\n")
+		}
+	}
+	out(w, "    default:\n")
+	out(w, "      return nil, rpc.BadRequest(\"Invalid method\")\n")
 	out(w, "  }\n")
 	out(w, "}\n")
 	return nil
 }
 
+// writeMetrics writes the constructor of method metrics registries to w.
+func writeMetrics(w io.Writer, s *schema.Schema) error {
+	out := fmt.Fprintf
+
+	var names []string
+	for _, m := range s.Methods {
+		names = append(names, fmt.Sprintf("%q", m.Name))
+	}
+
+	out(w, "\n")
+	out(w, "// NewMetrics returns a new registry of method metrics, recorded and served\n")
+	out(w, "// at /_metrics for servers returning it from rpc.MetricsProvider.\n")
+	out(w, "func NewMetrics() *metrics.Registry {\n")
+	out(w, "  return metrics.New(%s)\n", strings.Join(names, ", "))
+	out(w, "}\n")
+	return nil
+}
+
+// writeAuthenticator writes the authentication of requests by path to w,
+// and of batched calls when batch is true.
+func writeAuthenticator(w io.Writer, s *schema.Schema, batch bool) error {
+	out := fmt.Fprintf
+
+	var public []string
+	for _, m := range s.Methods {
+		if m.Auth == "none" {
+			public = append(public, fmt.Sprintf("%q", "/"+m.Name))
+		}
+	}
+
+	// batched calls are authenticated individually
+	if batch {
+		public = append(public, fmt.Sprintf("%q", "/_batch"))
+	}
+
+	out(w, "\n")
+	out(w, "// authenticate returns ctx with the principal of r, unless the method does not require authentication.\n")
+	out(w, "func (s *Server) authenticate(ctx context.Context, r *http.Request) (context.Context, error) {\n")
+	if len(public) > 0 {
+		out(w, "  switch r.URL.Path {\n")
+		out(w, "    case %s:\n", strings.Join(public, ", "))
+		out(w, "      return ctx, nil\n")
+		out(w, "  }\n\n")
+	}
+	out(w, "  return rpc.Authenticate(ctx, s, r)\n")
+	out(w, "}\n")
+
+	if batch {
+		out(w, "\n")
+		out(w, "// callBatched invokes a batched call of the method at path, authenticated like a single call.\n")
+		out(w, "func (s *Server) callBatched(ctx context.Context, path string, r *http.Request) (interface{}, error) {\n")
+		out(w, "  ctx, err := s.authenticate(ctx, r)\n")
+		out(w, "  if err != nil {\n")
+		out(w, "    return nil, err\n")
+		out(w, "  }\n")
+		out(w, "  return s.callMethod(ctx, path, r.WithContext(ctx))\n")
+		out(w, "}\n")
+	}
+	return nil
+}
+
+// scopes returns the formatted scopes required by method m.
+func scopes(m schema.Method) string {
+	var v []string
+	for _, s := range m.Scopes {
+		v = append(v, fmt.Sprintf("%q", s))
+	}
+	return strings.Join(v, ", ")
+}
+
+// safe returns true if method m is served by GET requests.
+func safe(m schema.Method) bool {
+	return m.Safe && !m.Stream
+}
+
+// cacheControl returns the Cache-Control header of responses to GET requests
+// of method m, which are revalidated by default.
+func cacheControl(m schema.Method) string {
+	if m.CacheControl != "" {
+		return m.CacheControl
+	}
+	return "no-cache"
+}
+
+// throttled returns true if method m has a rate or concurrency limit.
+func throttled(m schema.Method) bool {
+	return m.RateLimit.Rate > 0 || m.RateLimit.Concurrency > 0
+}
+
+// rateLimit returns the formatted rpc.RateLimit for method m.
+func rateLimit(m schema.Method) string {
+	var fields []string
+	l := m.RateLimit
+
+	if l.Rate > 0 {
+		fields = append(fields, fmt.Sprintf("Rate: %g", l.Rate))
+	}
+
+	if l.Burst > 0 {
+		fields = append(fields, fmt.Sprintf("Burst: %d", l.Burst))
+	}
+
+	if l.Concurrency > 0 {
+		fields = append(fields, fmt.Sprintf("Concurrency: %d", l.Concurrency))
+	}
+
+	return fmt.Sprintf("rpc.RateLimit{%s}", strings.Join(fields, ", "))
+}
+
+// methodInfo returns the formatted rpc.MethodInfo for method m.
+func methodInfo(m schema.Method) string {
+	fields := []string{fmt.Sprintf("Name: %q", m.Name)}
+
+	if m.Group != "" {
+		fields = append(fields, fmt.Sprintf("Group: %q", m.Group))
+	}
+
+	if m.Private {
+		fields = append(fields, "Private: true")
+	}
+
+	if m.Stream {
+		fields = append(fields, "Stream: true")
+	}
+
+	if m.Idempotent {
+		fields = append(fields, "Idempotent: true")
+	}
+
+	if len(m.Scopes) > 0 {
+		fields = append(fields, fmt.Sprintf("Scopes: []string{%s}", scopes(m)))
+	}
+
+	return fmt.Sprintf("rpc.MethodInfo{%s}", strings.Join(fields, ", "))
+}
+
+// readOptions returns the formatted rpc.ReadRequest options for method m.
+func readOptions(m schema.Method) string {
+	var fields []string
+
+	if l := m.Limits; l.MaxBytes > 0 {
+		fields = append(fields, fmt.Sprintf("MaxBytes: %d", l.MaxBytes))
+	}
+
+	if l := m.Limits; l.MaxDepth > 0 {
+		fields = append(fields, fmt.Sprintf("MaxDepth: %d", l.MaxDepth))
+	}
+
+	if l := m.Limits; l.MaxArrayLength > 0 {
+		fields = append(fields, fmt.Sprintf("MaxArrayLength: %d", l.MaxArrayLength))
+	}
+
+	var options string
+
+	if len(fields) > 0 {
+		options += fmt.Sprintf(", rpc.WithLimits(rpc.Limits{%s})", strings.Join(fields, ", "))
+	}
+
+	if m.Strict {
+		options += ", rpc.WithStrict()"
+	}
+
+	return options
+}
+
+// writeSenders writes the typed senders of streaming methods to w.
+func writeSenders(w io.Writer, s *schema.Schema, types string) error {
+	out := fmt.Fprintf
+
+	for _, m := range s.Methods {
+		if !m.Stream {
+			continue
+		}
+
+		name := format.GoName(m.Name)
+		out(w, "\n")
+		out(w, "// %sSender sends the outputs of the %s method.\n", name, m.Name)
+		out(w, "type %sSender struct {\n", name)
+		out(w, "  stream *rpc.Stream\n")
+		out(w, "}\n\n")
+		out(w, "// Send sends out, returning an error when the client has gone away.\n")
+		out(w, "func (s %sSender) Send(out *%s) error {\n", name, format.GoOutputType(types, m.Name))
+		out(w, "  return s.stream.Send(out)\n")
+		out(w, "}\n\n")
+		out(w, "// Flush flushes the response, returning an error when the client has gone away.\n")
+		out(w, "func (s %sSender) Flush() error {\n", name)
+		out(w, "  return s.stream.Flush()\n")
+		out(w, "}\n")
+	}
+
+	return nil
+}
+
 // writeMethods writes method stubs to w.
 func writeMethods(w io.Writer, s *schema.Schema, tracing bool, types string) error {
 	out := fmt.Fprintf
@@ -83,15 +483,23 @@ func writeMethods(w io.Writer, s *schema.Schema, tracing bool, types string) err
 		out(w, "// %s %s\n", format.JsName(m.Name), m.Description)
 
 		// method signature
-		if len(m.Inputs) > 0 {
+		switch {
+		case m.Stream && len(m.Inputs) > 0:
+			out(w, "func (s *Server) %s(ctx context.Context, in %s, stream %sSender) error {\n", format.JsName(m.Name), format.GoInputType(types, m.Name), format.GoName(m.Name))
+		case m.Stream:
+			out(w, "func (s *Server) %s(ctx context.Context, stream %sSender) error {\n", format.JsName(m.Name), format.GoName(m.Name))
+		case len(m.Inputs) > 0:
 			out(w, "func (s *Server) %s(ctx context.Context, in %s) (interface{}, error) {\n", format.JsName(m.Name), format.GoInputType(types, m.Name))
-		} else {
+		default:
 			out(w, "func (s *Server) %s(ctx context.Context) (interface{}, error) {\n", format.JsName(m.Name))
 		}
 
 		// tracing
 		if tracing {
-			out(w, "  logs := log.FromContext(ctx).WithField(\"method\", %q)\n\n", m.Name)
+			out(w, "  logs := log.FromContext(ctx).WithFields(log.Fields{\n")
+			out(w, "    \"method\": %q,\n", m.Name)
+			out(w, "    \"request_id\": rpc.RequestIDFromContext(ctx),\n")
+			out(w, "  })\n\n")
 
 			if len(m.Inputs) > 0 {
 				out(w, "  logs = logs.WithFields(log.Fields{\n")
@@ -104,29 +512,32 @@ func writeMethods(w io.Writer, s *schema.Schema, tracing bool, types string) err
 		}
 
 		// invoke method
-		if len(m.Outputs) > 0 {
+		if len(m.Outputs) > 0 && !m.Stream {
 			out(w, "  res, err := s.%s", format.GoName(m.Name))
 		} else {
 			out(w, "  err := s.%s", format.GoName(m.Name))
 		}
 
+		var args []string
 		if tracing {
-			if len(m.Inputs) > 0 {
-				out(w, "(log.NewContext(ctx, logs), in)\n")
-			} else {
-				out(w, "(log.NewContext(ctx, logs))\n")
-			}
+			args = append(args, "log.NewContext(ctx, logs)")
 		} else {
-			if len(m.Inputs) > 0 {
-				out(w, "(ctx, in)\n")
-			} else {
-				out(w, "(ctx)\n")
-			}
+			args = append(args, "ctx")
+		}
+		if len(m.Inputs) > 0 {
+			args = append(args, "in")
 		}
+		if m.Stream {
+			args = append(args, "stream")
+		}
+		out(w, "(%s)\n", strings.Join(args, ", "))
 
-		if len(m.Outputs) > 0 {
+		switch {
+		case m.Stream:
+			out(w, "  return err\n")
+		case len(m.Outputs) > 0:
 			out(w, "  return res, err\n")
-		} else {
+		default:
 			out(w, "  return nil, err\n")
 		}
 
@@ -16,7 +16,7 @@ func TestGenerate_noTypes(t *testing.T) {
 	assert.NoError(t, err, "loading schema")
 
 	var act bytes.Buffer
-	err = goserver.Generate(&act, schema, false, "")
+	err = goserver.Generate(&act, schema, false, "", false)
 	assert.NoError(t, err, "generating")
 
 	fixture.Assert(t, "todo_server_no_types.go", act.Bytes())
@@ -26,7 +26,7 @@ func TestGenerate_types(t *testing.T) {
 	assert.NoError(t, err, "loading schema")
 
 	var act bytes.Buffer
-	err = goserver.Generate(&act, schema, false, "api")
+	err = goserver.Generate(&act, schema, false, "api", true)
 	assert.NoError(t, err, "generating")
 
 	fixture.Assert(t, "todo_server_types.go", act.Bytes())
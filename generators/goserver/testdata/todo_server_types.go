@@ -1,38 +1,82 @@
 // ServeHTTP implementation.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  w = rpc.Negotiate(w, r)
+
+  if rpc.HandleCORS(w, r, s) {
+    return
+  }
+
+  var cacheControl string
+
   if r.Method == "GET" {
     switch r.URL.Path {
       case "/_health":
         rpc.WriteHealth(w, s)
+        return
+      case "/_health/live":
+        rpc.WriteHealthReport(w, r, s, rpc.Liveness, "todo", "1.0.0")
+        return
+      case "/_health/ready":
+        rpc.WriteHealthReport(w, r, s, rpc.Readiness, "todo", "1.0.0")
+        return
+      case "/_metrics":
+        if rpc.ServeMetrics(w, r, s) {
+          return
+        }
+        rpc.WriteError(w, rpc.BadRequest("Invalid method"))
+        return
+      case "/get_items":
+        cacheControl = "no-cache"
+      case "/_batch", "/add_item", "/remove_item", "/watch_items":
+        rpc.WriteError(w, rpc.MethodNotAllowed("Method not allowed", "POST", "OPTIONS"))
+        return
       default:
         rpc.WriteError(w, rpc.BadRequest("Invalid method"))
+        return
     }
-    return
   }
 
-  if r.Method == "POST" {
-    ctx := rpc.NewRequestContext(r.Context(), r)
+  if r.Method == "POST" || r.Method == "GET" {
+    ctx, cancel := rpc.WithTimeout(r.Context(), r)
+    defer cancel()
+    r = r.WithContext(ctx)
+    ctx = rpc.NewRequestContext(ctx, r)
+    ctx = rpc.NewTraceContext(ctx, r)
+    ctx, err := s.authenticate(ctx, r)
+    if err != nil {
+      rpc.WriteError(w, err)
+      return
+    }
+    r = r.WithContext(ctx)
     var res interface{}
-    var err error
     switch r.URL.Path {
+      case "/_batch":
+        rpc.ServeBatch(w, r, s.callBatched)
+        return
       case "/add_item":
-        var in api.AddItemInput
-        err = rpc.ReadRequest(r, &in)
-        if err != nil {
-          break
-        }
-        res, err = s.addItem(ctx, in)
-      case "/get_items":
-        res, err = s.getItems(ctx)
-      case "/remove_item":
-        var in api.RemoveItemInput
-        err = rpc.ReadRequest(r, &in)
+        rpc.ServeIdempotent(w, r, s, func() (interface{}, error) {
+          return s.callMethod(ctx, r.URL.Path, r)
+        }, rpc.WithLimits(rpc.Limits{MaxBytes: 4096}), rpc.WithStrict())
+        return
+      case "/watch_items":
+        done := rpc.BeginMetrics(s, r.URL.Path)
+        defer func() {
+          done(err)
+        }()
+        var release func()
+        release, err = rpc.Throttle(ctx, s, "watch_items", rpc.RateLimit{Concurrency: 100})
         if err != nil {
           break
         }
-        res, err = s.removeItem(ctx, in)
+        defer release()
+        stream := rpc.NewStream(w, r)
+        _, err = rpc.Intercept(ctx, s, rpc.MethodInfo{Name: "watch_items", Stream: true}, nil, func(ctx context.Context, in interface{}) (interface{}, error) {
+          return nil, s.watchItems(ctx, WatchItemsSender{stream})
+        })
+        stream.Finish(err)
+        return
       default:
-        err = rpc.BadRequest("Invalid method")
+        res, err = s.callMethod(ctx, r.URL.Path, r)
     }
 
     if err != nil {
@@ -40,9 +84,107 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
       return
     }
 
+    if r.Method == "GET" {
+      rpc.WriteCacheable(w, r, res, cacheControl)
+      return
+    }
+
     rpc.WriteResponse(w, res)
     return
   }
+
+  if r.Method == "OPTIONS" {
+    w.Header().Set("Allow", "GET, POST, OPTIONS")
+    w.WriteHeader(http.StatusNoContent)
+    return
+  }
+
+  rpc.WriteError(w, rpc.MethodNotAllowed("Method not allowed", "GET", "POST", "OPTIONS"))
+}
+
+// callMethod invokes the method at path with the input read from r.
+func (s *Server) callMethod(ctx context.Context, path string, r *http.Request) (res interface{}, err error) {
+  done := rpc.BeginMetrics(s, path)
+  defer func() {
+    done(err)
+  }()
+
+  switch path {
+    case "/add_item":
+      release, err := rpc.Throttle(ctx, s, "add_item", rpc.RateLimit{Rate: 10, Burst: 20})
+      if err != nil {
+        return nil, err
+      }
+      defer release()
+      var in api.AddItemInput
+      err = rpc.ReadRequest(r, &in, rpc.WithLimits(rpc.Limits{MaxBytes: 4096}), rpc.WithStrict())
+      if err != nil {
+        return nil, err
+      }
+      return rpc.Intercept(ctx, s, rpc.MethodInfo{Name: "add_item", Idempotent: true}, in, func(ctx context.Context, in interface{}) (interface{}, error) {
+        return s.addItem(ctx, in.(api.AddItemInput))
+      })
+    case "/get_items":
+      return rpc.Intercept(ctx, s, rpc.MethodInfo{Name: "get_items"}, nil, func(ctx context.Context, in interface{}) (interface{}, error) {
+        return s.getItems(ctx)
+      })
+    case "/remove_item":
+      if err := rpc.Authorize(ctx, "items:write"); err != nil {
+        return nil, err
+      }
+      var in api.RemoveItemInput
+      err := rpc.ReadRequest(r, &in)
+      if err != nil {
+        return nil, err
+      }
+      return rpc.Intercept(ctx, s, rpc.MethodInfo{Name: "remove_item", Scopes: []string{"items:write"}}, in, func(ctx context.Context, in interface{}) (interface{}, error) {
+        return s.removeItem(ctx, in.(api.RemoveItemInput))
+      })
+    case "/watch_items":
+      return nil, rpc.BadRequest("Streaming methods must not be batched")
+    default:
+      return nil, rpc.BadRequest("Invalid method")
+  }
+}
+
+// NewMetrics returns a new registry of method metrics, recorded and served
+// at /_metrics for servers returning it from rpc.MetricsProvider.
+func NewMetrics() *metrics.Registry {
+  return metrics.New("add_item", "get_items", "remove_item", "watch_items")
+}
+
+// authenticate returns ctx with the principal of r, unless the method does not require authentication.
+func (s *Server) authenticate(ctx context.Context, r *http.Request) (context.Context, error) {
+  switch r.URL.Path {
+    case "/get_items", "/_batch":
+      return ctx, nil
+  }
+
+  return rpc.Authenticate(ctx, s, r)
+}
+
+// callBatched invokes a batched call of the method at path, authenticated like a single call.
+func (s *Server) callBatched(ctx context.Context, path string, r *http.Request) (interface{}, error) {
+  ctx, err := s.authenticate(ctx, r)
+  if err != nil {
+    return nil, err
+  }
+  return s.callMethod(ctx, path, r.WithContext(ctx))
+}
+
+// WatchItemsSender sends the outputs of the watch_items method.
+type WatchItemsSender struct {
+  stream *rpc.Stream
+}
+
+// Send sends out, returning an error when the client has gone away.
+func (s WatchItemsSender) Send(out *api.WatchItemsOutput) error {
+  return s.stream.Send(out)
+}
+
+// Flush flushes the response, returning an error when the client has gone away.
+func (s WatchItemsSender) Flush() error {
+  return s.stream.Flush()
 }
 
 // addItem adds an item to the list.
@@ -63,3 +205,9 @@ func (s *Server) removeItem(ctx context.Context, in api.RemoveItemInput) (interf
   return res, err
 }
 
+// watchItems streams items as they are added to the list.
+func (s *Server) watchItems(ctx context.Context, stream WatchItemsSender) error {
+  err := s.WatchItems(ctx, stream)
+  return err
+}
+
@@ -42,6 +42,12 @@ func Generate(w io.Writer, s *schema.Schema, validate bool) error {
 		}
 	}
 
+	// errors
+	for _, e := range s.ErrorsSlice() {
+		writeError(w, s, e)
+		out(w, "\n")
+	}
+
 	// methods
 	for _, m := range s.Methods {
 		name := format.GoName(m.Name)
@@ -81,6 +87,64 @@ func Generate(w io.Writer, s *schema.Schema, validate bool) error {
 	return nil
 }
 
+// writeError writes an error type, its constructor and methods to w.
+func writeError(w io.Writer, s *schema.Schema, e schema.Error) {
+	out := fmt.Fprintf
+	name := format.GoName(e.Name) + "Error"
+
+	// type
+	out(w, "// %s %s\n", name, e.Description)
+	out(w, "type %s struct {\n", name)
+	out(w, "  // Message is the error message.\n")
+	out(w, "  Message string `json:\"message\"`\n")
+	if len(e.Details) > 0 {
+		out(w, "\n")
+		out(w, "  // Details is the error details.\n")
+		out(w, "  Details %sDetails `json:\"details\"`\n", name)
+	}
+	out(w, "}\n\n")
+
+	// details
+	if len(e.Details) > 0 {
+		out(w, "// %sDetails is the details of %s.\n", name, name)
+		out(w, "type %sDetails struct {\n", name)
+		writeFields(w, s, e.Details)
+		out(w, "}\n\n")
+	}
+
+	// constructor
+	out(w, "// New%s returns a new %s with message.\n", name, name)
+	if len(e.Details) > 0 {
+		out(w, "func New%s(message string, details %sDetails) error {\n", name, name)
+		out(w, "  return %s{Message: message, Details: details}\n", name)
+	} else {
+		out(w, "func New%s(message string) error {\n", name)
+		out(w, "  return %s{Message: message}\n", name)
+	}
+	out(w, "}\n\n")
+
+	// methods
+	out(w, "// Error implementation.\n")
+	out(w, "func (e %s) Error() string {\n", name)
+	out(w, "  return e.Message\n")
+	out(w, "}\n\n")
+	out(w, "// StatusCode implementation.\n")
+	out(w, "func (e %s) StatusCode() int {\n", name)
+	out(w, "  return %d\n", e.Status)
+	out(w, "}\n\n")
+	out(w, "// Type implementation.\n")
+	out(w, "func (e %s) Type() string {\n", name)
+	out(w, "  return %q\n", e.Name)
+	out(w, "}\n")
+	if len(e.Details) > 0 {
+		out(w, "\n")
+		out(w, "// ErrorDetails implementation.\n")
+		out(w, "func (e %s) ErrorDetails() interface{} {\n", name)
+		out(w, "  return e.Details\n")
+		out(w, "}\n")
+	}
+}
+
 // writeFields to writer.
 func writeFields(w io.Writer, s *schema.Schema, fields []schema.Field) {
 	for i, f := range fields {
@@ -158,11 +222,12 @@ func writeValidation(w io.Writer, name string, fields []schema.Field) error {
 	recv := strings.ToLower(name)[0]
 	out(w, "// Validate implementation.\n")
 	out(w, "func (%c *%s) Validate() error {\n", recv, name)
+	out(w, "  var errs rpc.ValidationErrors\n\n")
 	for _, f := range fields {
 		writeFieldDefaults(w, f, recv)
 		writeFieldValidation(w, f, recv)
 	}
-	out(w, "  return nil\n")
+	out(w, "  return errs.Err()\n")
 	out(w, "}\n")
 	return nil
 }
@@ -197,7 +262,7 @@ func writeFieldValidation(w io.Writer, f schema.Field, recv byte) error {
 	name := format.GoName(f.Name)
 
 	writeError := func(msg string) {
-		out(w, "    return rpc.ValidationError{ Field: %q, Message: %q }\n", f.Name, msg)
+		out(w, "    errs.Add(%q, %q)\n", f.Name, msg)
 	}
 
 	// required
@@ -230,13 +295,16 @@ func writeFieldValidation(w io.Writer, f schema.Field, recv byte) error {
 		out(w, "  }\n\n")
 	}
 
+	// validate non-primitive values
+	if f.Type.Ref.Value != "" {
+		out(w, "  errs.Merge(%q, %c.%s.Validate())\n\n", "/"+f.Name, recv, name)
+	}
+
 	// validate the children of non-primitive arrays
 	// TODO: HasRef() or similar?
 	if f.Type.Type == schema.Array && f.Items.Ref.Value != "" {
 		out(w, "  for i, v := range %c.%s {\n", recv, name)
-		out(w, "    if err := v.Validate(); err != nil {\n")
-		out(w, "      return fmt.Errorf(\"element %%d: %%s\", i, err.Error())\n")
-		out(w, "    }\n")
+		out(w, "    errs.Merge(fmt.Sprintf(\"/%s/%%d\", i), v.Validate())\n", f.Name)
 		out(w, "  }\n\n")
 	}
 
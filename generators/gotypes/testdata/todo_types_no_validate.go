@@ -10,6 +10,46 @@ type Item struct {
   Text string `json:"text"`
 }
 
+// ItemNotFoundError is returned when the item does not exist.
+type ItemNotFoundError struct {
+  // Message is the error message.
+  Message string `json:"message"`
+
+  // Details is the error details.
+  Details ItemNotFoundErrorDetails `json:"details"`
+}
+
+// ItemNotFoundErrorDetails is the details of ItemNotFoundError.
+type ItemNotFoundErrorDetails struct {
+  // ID is the id of the item.
+  ID int `json:"id"`
+}
+
+// NewItemNotFoundError returns a new ItemNotFoundError with message.
+func NewItemNotFoundError(message string, details ItemNotFoundErrorDetails) error {
+  return ItemNotFoundError{Message: message, Details: details}
+}
+
+// Error implementation.
+func (e ItemNotFoundError) Error() string {
+  return e.Message
+}
+
+// StatusCode implementation.
+func (e ItemNotFoundError) StatusCode() int {
+  return 404
+}
+
+// Type implementation.
+func (e ItemNotFoundError) Type() string {
+  return "item_not_found"
+}
+
+// ErrorDetails implementation.
+func (e ItemNotFoundError) ErrorDetails() interface{} {
+  return e.Details
+}
+
 // AddItemInput params.
 type AddItemInput struct {
   // Item is the item to add. This field is required.
@@ -34,3 +74,9 @@ type RemoveItemOutput struct {
   Item Item `json:"item"`
 }
 
+// WatchItemsOutput params.
+type WatchItemsOutput struct {
+  // Item is the item added.
+  Item Item `json:"item"`
+}
+
@@ -12,11 +12,53 @@ type Item struct {
 
 // Validate implementation.
 func (i *Item) Validate() error {
+  var errs rpc.ValidationErrors
+
   if i.Text == "" {
-    return rpc.ValidationError{ Field: "text", Message: "is required" }
+    errs.Add("text", "is required")
   }
 
-  return nil
+  return errs.Err()
+}
+
+// ItemNotFoundError is returned when the item does not exist.
+type ItemNotFoundError struct {
+  // Message is the error message.
+  Message string `json:"message"`
+
+  // Details is the error details.
+  Details ItemNotFoundErrorDetails `json:"details"`
+}
+
+// ItemNotFoundErrorDetails is the details of ItemNotFoundError.
+type ItemNotFoundErrorDetails struct {
+  // ID is the id of the item.
+  ID int `json:"id"`
+}
+
+// NewItemNotFoundError returns a new ItemNotFoundError with message.
+func NewItemNotFoundError(message string, details ItemNotFoundErrorDetails) error {
+  return ItemNotFoundError{Message: message, Details: details}
+}
+
+// Error implementation.
+func (e ItemNotFoundError) Error() string {
+  return e.Message
+}
+
+// StatusCode implementation.
+func (e ItemNotFoundError) StatusCode() int {
+  return 404
+}
+
+// Type implementation.
+func (e ItemNotFoundError) Type() string {
+  return "item_not_found"
+}
+
+// ErrorDetails implementation.
+func (e ItemNotFoundError) ErrorDetails() interface{} {
+  return e.Details
 }
 
 // AddItemInput params.
@@ -27,11 +69,13 @@ type AddItemInput struct {
 
 // Validate implementation.
 func (a *AddItemInput) Validate() error {
+  var errs rpc.ValidationErrors
+
   if a.Item == "" {
-    return rpc.ValidationError{ Field: "item", Message: "is required" }
+    errs.Add("item", "is required")
   }
 
-  return nil
+  return errs.Err()
 }
 
 // GetItemsOutput params.
@@ -48,7 +92,9 @@ type RemoveItemInput struct {
 
 // Validate implementation.
 func (r *RemoveItemInput) Validate() error {
-  return nil
+  var errs rpc.ValidationErrors
+
+  return errs.Err()
 }
 
 // RemoveItemOutput params.
@@ -57,6 +103,12 @@ type RemoveItemOutput struct {
   Item Item `json:"item"`
 }
 
+// WatchItemsOutput params.
+type WatchItemsOutput struct {
+  // Item is the item added.
+  Item Item `json:"item"`
+}
+
 
 // oneOf returns true if s is in the values.
 func oneOf(s string, values []string) bool {
@@ -46,7 +46,7 @@ func Generate(s *schema.Schema, dir string) error {
 
 	// methods
 	for _, m := range s.Methods {
-		if err := generateMethod(m, methodsDir); err != nil {
+		if err := generateMethod(s, m, methodsDir); err != nil {
 			return fmt.Errorf("generating methods: %w", err)
 		}
 	}
@@ -155,7 +155,7 @@ func writeMethodIndex(w io.Writer, s *schema.Schema) {
 }
 
 // generateMethod generates method documentation.
-func generateMethod(m schema.Method, dir string) error {
+func generateMethod(s *schema.Schema, m schema.Method, dir string) error {
 	path := filepath.Join(dir, m.Name+".md")
 
 	fmt.Printf("  ==> Create %s\n", path)
@@ -165,15 +165,44 @@ func generateMethod(m schema.Method, dir string) error {
 	}
 	defer f.Close()
 
-	writeMethod(f, m)
+	writeMethod(f, s, m)
 	return nil
 }
 
 // writeMethod writes method documentation to w.
-func writeMethod(w io.Writer, m schema.Method) {
+func writeMethod(w io.Writer, s *schema.Schema, m schema.Method) {
 	fmt.Fprintf(w, "# %s\n\n", m.Name)
 	fmt.Fprintf(w, "The `%s` method %s\n\n", m.Name, m.Description)
 
+	if m.Stream {
+		fmt.Fprintf(w, "  This method streams its outputs as newline-delimited JSON, or Server-Sent Events.\n\n")
+	}
+
+	if m.Auth == "none" {
+		fmt.Fprintf(w, "  This method does not require authentication.\n\n")
+	}
+
+	if m.Strict {
+		fmt.Fprintf(w, "  This method rejects inputs with unknown fields.\n\n")
+	}
+
+	if m.Safe && !m.Stream {
+		fmt.Fprintf(w, "  This method is safe, it may also be called with a `GET` request passing its inputs as query-string parameters, and its responses may be cached.\n\n")
+	}
+
+	if m.Idempotent {
+		fmt.Fprintf(w, "  This method is idempotent, retries sending the same `Idempotency-Key` header receive the response of the first call.\n\n")
+	}
+
+	// scopes
+	if len(m.Scopes) > 0 {
+		fmt.Fprintf(w, "  Scopes:\n\n")
+		for _, scope := range m.Scopes {
+			fmt.Fprintf(w, "  - `%s`\n", scope)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+
 	// inputs
 	if len(m.Inputs) > 0 {
 		fmt.Fprintf(w, "  Inputs:\n\n")
@@ -193,6 +222,18 @@ func writeMethod(w io.Writer, m schema.Method) {
 		}
 	}
 
+	// errors
+	if len(m.Errors) > 0 {
+		if len(m.Outputs) > 0 {
+			fmt.Fprintf(w, "\n")
+		}
+		fmt.Fprintf(w, "  Errors:\n\n")
+		writeTableHeader(w, "Type", "Status", "Description")
+		for _, e := range schemautil.ResolveErrors(s, m) {
+			writeTableRow(w, fmt.Sprintf("`%s`", e.Name), fmt.Sprintf("__%d__", e.Status), capitalize(e.Description))
+		}
+	}
+
 	writeMethodExamples(w, m.Examples)
 	fmt.Fprintf(w, "\n")
 }
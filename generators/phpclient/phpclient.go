@@ -20,7 +20,8 @@ var call = `
       'http' => array(
         'header'  => $header,
         'method'  => 'POST',
-        'content' => json_encode($body)
+        'content' => json_encode($body),
+        'ignore_errors' => true
       )
     );
 
@@ -28,11 +29,56 @@ var call = `
     $context = stream_context_create($options);
     $result = file_get_contents($url, false, $context);
 
-    // TODO: how to check the status code and parse error from the body?
+    if ($result === false) {
+      throw new %[1]sError(0, null, "Request to $url failed");
+    }
+
+    // the status line and headers of the last response
+    $status = 0;
+    $requestId = null;
+    foreach ($http_response_header as $line) {
+      if (preg_match('#^HTTP/\S+\s+(\d+)#', $line, $m)) {
+        $status = intval($m[1]);
+        $requestId = null;
+      } else if (stripos($line, 'X-Request-Id:') === 0) {
+        $requestId = trim(substr($line, 13));
+      }
+    }
+
+    if ($status >= 400) {
+      $error = json_decode($result, true);
+      if (!is_array($error)) {
+        throw new %[1]sError($status, null, null, $requestId);
+      }
+      if (isset($error['request_id'])) {
+        $requestId = $error['request_id'];
+      }
+      throw new %[1]sError($status, $error['type'] ?? null, $error['message'] ?? null, $requestId);
+    }
 
     return json_decode($result);
   }`
 
+var exception = `
+/**
+ * %[1]sError is thrown when an API call fails with a 4xx or 5xx HTTP status,
+ * providing the error type and the request ID of the response.
+ */
+
+class %[1]sError extends Exception {
+  public $status;
+  public $type;
+  public $requestId;
+
+  public function __construct($status, $type = null, $message = null, $requestId = null) {
+    parent::__construct($message ?? "$status response", $status);
+    $this->status = $status;
+    $this->type = $type;
+    $this->requestId = $requestId;
+  }
+}
+`
+
 var class = `
 class %s {
   protected $url;
@@ -58,9 +104,15 @@ func Generate(w io.Writer, s *schema.Schema, className string) error {
 	out(w, "<?php\n")
 	out(w, "// Do not edit, this file was generated by github.com/apex/rpc.\n")
 
+	out(w, exception, className)
 	out(w, class, className)
 
 	for _, m := range s.Methods {
+		// streaming methods are not supported by this client
+		if m.Stream {
+			continue
+		}
+
 		name := format.JsName(m.Name)
 
 		// comment
@@ -68,6 +120,10 @@ func Generate(w io.Writer, s *schema.Schema, className string) error {
 		out(w, "  /**\n")
 		out(w, "   * %s %s\n", name, m.Description)
 		out(w, "   *\n")
+		if len(m.Scopes) > 0 {
+			out(w, "   * %s\n", format.Scopes(m.Scopes))
+			out(w, "   *\n")
+		}
 		if len(m.Inputs) > 0 {
 			out(w, "   * @param array $params The input parameters.\n")
 		}
@@ -96,7 +152,7 @@ func Generate(w io.Writer, s *schema.Schema, className string) error {
 		out(w, "  }\n")
 	}
 
-	out(w, "%s\n", call)
+	out(w, call+"\n", className)
 	out(w, "}\n")
 
 	return nil
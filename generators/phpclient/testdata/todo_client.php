@@ -1,6 +1,24 @@
 <?php
 // Do not edit, this file was generated by github.com/apex/rpc.
 
+/**
+ * ClientError is thrown when an API call fails with a 4xx or 5xx HTTP status,
+ * providing the error type and the request ID of the response.
+ */
+
+class ClientError extends Exception {
+  public $status;
+  public $type;
+  public $requestId;
+
+  public function __construct($status, $type = null, $message = null, $requestId = null) {
+    parent::__construct($message ?? "$status response", $status);
+    $this->status = $status;
+    $this->type = $type;
+    $this->requestId = $requestId;
+  }
+}
+
 class Client {
   protected $url;
   protected $authToken;
@@ -38,6 +56,8 @@ class Client {
   /**
    * removeItem removes an item from the to-do list.
    *
+   * Requires the items:write scope.
+   *
    * @param array $params The input parameters.
    * @return array
    */
@@ -56,7 +76,8 @@ class Client {
       'http' => array(
         'header'  => $header,
         'method'  => 'POST',
-        'content' => json_encode($body)
+        'content' => json_encode($body),
+        'ignore_errors' => true
       )
     );
 
@@ -64,7 +85,32 @@ class Client {
     $context = stream_context_create($options);
     $result = file_get_contents($url, false, $context);
 
-    // TODO: how to check the status code and parse error from the body?
+    if ($result === false) {
+      throw new ClientError(0, null, "Request to $url failed");
+    }
+
+    // the status line and headers of the last response
+    $status = 0;
+    $requestId = null;
+    foreach ($http_response_header as $line) {
+      if (preg_match('#^HTTP/\S+\s+(\d+)#', $line, $m)) {
+        $status = intval($m[1]);
+        $requestId = null;
+      } else if (stripos($line, 'X-Request-Id:') === 0) {
+        $requestId = trim(substr($line, 13));
+      }
+    }
+
+    if ($status >= 400) {
+      $error = json_decode($result, true);
+      if (!is_array($error)) {
+        throw new ClientError($status, null, null, $requestId);
+      }
+      if (isset($error['request_id'])) {
+        $requestId = $error['request_id'];
+      }
+      throw new ClientError($status, $error['type'] ?? null, $error['message'] ?? null, $requestId);
+    }
 
     return json_decode($result);
   }
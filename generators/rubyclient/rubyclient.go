@@ -5,6 +5,8 @@ import (
 	"io"
 	"strings"
 
+	"github.com/apex/rpc/internal/format"
+	"github.com/apex/rpc/internal/schemautil"
 	"github.com/apex/rpc/schema"
 )
 
@@ -22,11 +24,15 @@ module %s
       attr_reader :type
       attr_reader :message
       attr_reader :status
+      attr_reader :details
+      attr_reader :request_id
 
-      def initialize(status, type = nil, message = nil)
+      def initialize(status, type = nil, message = nil, details = nil, request_id = nil)
         @status = status
         @type = type
         @message = message
+        @details = details
+        @request_id = request_id
       end
 
       def to_s
@@ -61,12 +67,16 @@ var call = `
       status = res.code.to_i
   
       if status >= 400
+        request_id = res["X-Request-Id"]
         begin
           body = JSON.parse(res.body)
         rescue
-          raise Error.new(status)
+          raise Error.new(status, nil, nil, nil, request_id)
         end
-        raise Error.new(status, body["type"], body["message"])
+        request_id = body["request_id"] || request_id
+        error = ERRORS[body["type"]]
+        raise error.new(status, body["message"], body["details"], request_id) if error
+        raise Error.new(status, body["type"], body["message"], body["details"], request_id)
       end
   
       res.body
@@ -79,10 +89,40 @@ func Generate(w io.Writer, s *schema.Schema, moduleName, className string) error
 
 	out(w, module, moduleName, className)
 
+	// errors
+	errors := s.ErrorsSlice()
+	for _, e := range errors {
+		name := format.GoName(e.Name) + "Error"
+		out(w, "\n")
+		out(w, "    # %s %s\n", name, e.Description)
+		out(w, "    class %s < Error\n", name)
+		out(w, "      def initialize(status, message = nil, details = nil, request_id = nil)\n")
+		out(w, "        super(status, %q, message, details, request_id)\n", e.Name)
+		out(w, "      end\n")
+		out(w, "    end\n")
+	}
+
+	out(w, "\n")
+	out(w, "    # ERRORS maps the error types declared in the schema to their classes.\n")
+	out(w, "    ERRORS = {\n")
+	for _, e := range errors {
+		out(w, "      %q => %sError,\n", e.Name, format.GoName(e.Name))
+	}
+	out(w, "    }\n")
+
 	for _, m := range s.Methods {
+		// streaming methods are not supported by this client
+		if m.Stream {
+			continue
+		}
+
 		// comment
 		out(w, "\n")
 		out(w, "    # %s\n", capitalize(m.Description))
+		if len(m.Scopes) > 0 {
+			out(w, "    #\n")
+			out(w, "    # %s\n", format.Scopes(m.Scopes))
+		}
 		if len(m.Inputs) > 0 {
 			out(w, "    #\n")
 			out(w, "    # @param [Hash] params the input for this method.\n")
@@ -90,6 +130,12 @@ func Generate(w io.Writer, s *schema.Schema, moduleName, className string) error
 				out(w, "    # @param params [%s] :%s %s\n", rubyType(s, f), f.Name, capitalize(f.Description))
 			}
 		}
+		if len(m.Errors) > 0 {
+			out(w, "    #\n")
+			for _, e := range schemautil.ResolveErrors(s, m) {
+				out(w, "    # @raise [%sError] %s\n", format.GoName(e.Name), capitalize(e.Description))
+			}
+		}
 
 		// method
 		out(w, "    def %s", m.Name)
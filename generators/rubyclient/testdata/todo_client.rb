@@ -12,11 +12,15 @@ module Todo
       attr_reader :type
       attr_reader :message
       attr_reader :status
+      attr_reader :details
+      attr_reader :request_id
 
-      def initialize(status, type = nil, message = nil)
+      def initialize(status, type = nil, message = nil, details = nil, request_id = nil)
         @status = status
         @type = type
         @message = message
+        @details = details
+        @request_id = request_id
       end
 
       def to_s
@@ -34,6 +38,18 @@ module Todo
       @auth_token = auth_token
     end
 
+    # ItemNotFoundError is returned when the item does not exist.
+    class ItemNotFoundError < Error
+      def initialize(status, message = nil, details = nil, request_id = nil)
+        super(status, "item_not_found", message, details, request_id)
+      end
+    end
+
+    # ERRORS maps the error types declared in the schema to their classes.
+    ERRORS = {
+      "item_not_found" => ItemNotFoundError,
+    }
+
     # Adds an item to the list.
     #
     # @param [Hash] params the input for this method.
@@ -49,8 +65,12 @@ module Todo
 
     # Removes an item from the to-do list.
     #
+    # Requires the items:write scope.
+    #
     # @param [Hash] params the input for this method.
     # @param params [Number] :id The id of the item to remove.
+    #
+    # @raise [ItemNotFoundError] Is returned when the item does not exist.
     def remove_item(params)
       call "remove_item", params
     end
@@ -70,12 +90,16 @@ module Todo
       status = res.code.to_i
   
       if status >= 400
+        request_id = res["X-Request-Id"]
         begin
           body = JSON.parse(res.body)
         rescue
-          raise Error.new(status)
+          raise Error.new(status, nil, nil, nil, request_id)
         end
-        raise Error.new(status, body["type"], body["message"])
+        request_id = body["request_id"] || request_id
+        error = ERRORS[body["type"]]
+        raise error.new(status, body["message"], body["details"], request_id) if error
+        raise Error.new(status, body["type"], body["message"], body["details"], request_id)
       end
   
       res.body
@@ -11,11 +11,38 @@ import (
 var error_handling = `// Error is an error returned by the client.
 #[derive(Serialize, Deserialize, Debug, Clone, Default)]
 pub struct ClientError {
+    #[serde(default)]
     status: String,
+    #[serde(default)]
     status_code: u16,
     #[serde(rename = "type")]
     err_type: Option<String>,
     message: Option<String>,
+    #[serde(default)]
+    fields: Vec<FieldError>,
+    #[serde(default)]
+    details: serde_json::Value,
+    request_id: Option<String>,
+}
+
+impl ClientError {
+    // fields returns the field validation errors.
+    pub fn fields(&self) -> &[FieldError] {
+        &self.fields
+    }
+
+    // request_id returns the id of the request, if any.
+    pub fn request_id(&self) -> Option<&str> {
+        self.request_id.as_deref()
+    }
+}
+
+// FieldError is a field validation error, where path is a JSON pointer to the field.
+#[derive(Serialize, Deserialize, Debug, Clone, Default)]
+pub struct FieldError {
+    pub path: String,
+    pub field: String,
+    pub message: String,
 }
 
 impl From<serde_json::error::Error> for ClientError {
@@ -25,6 +52,23 @@ impl From<serde_json::error::Error> for ClientError {
             status_code: 500,
             err_type: Some("serde".into()),
             message: Some(err.to_string()),
+            fields: Vec::new(),
+            details: serde_json::Value::Null,
+            request_id: None,
+        }
+    }
+}
+
+impl From<std::io::Error> for ClientError {
+    fn from(err: std::io::Error) -> ClientError {
+        ClientError {
+            status: "Internal Server Error".into(),
+            status_code: 500,
+            err_type: Some("io".into()),
+            message: Some(err.to_string()),
+            fields: Vec::new(),
+            details: serde_json::Value::Null,
+            request_id: None,
         }
     }
 }
@@ -36,26 +80,60 @@ impl From<reqwest::Error> for ClientError {
             status_code: 500,
             err_type: Some("reqwest".into()),
             message: Some(err.to_string()),
+            fields: Vec::new(),
+            details: serde_json::Value::Null,
+            request_id: None,
         }
     }
 }
 `
 
+var options = `// CallOptions are the options of a call. The timeout overrides that of the client,
+// and is sent in the Rpc-Timeout header so the server stops work the client no
+// longer awaits.
+#[derive(Debug, Clone, Default)]
+pub struct CallOptions {
+    pub timeout: Option<std::time::Duration>,
+}
+`
+
+var compression = `// COMPRESSION_THRESHOLD is the minimum size in bytes of a request body before it is compressed.
+const COMPRESSION_THRESHOLD: usize = 1024;
+`
+
 var call = `    // call implementation.
     async fn call(
         &self,
         method: &str,
         input: Option<Vec<u8>>,
+        options: &CallOptions,
     ) -> Result<bytes::Bytes, ClientError> {
+        use std::io::{Read, Write};
+
         let uri = format!("{}/{}", self.endpoint, method);
 
         let mut builder = self
             .client
             .post(&uri)
-            .header("Content-Type", "application/json");
+            .header("Content-Type", "application/json")
+            .header("Accept-Encoding", "gzip");
 
         if let Some(data) = input {
-            builder = builder.body(data);
+            if data.len() >= COMPRESSION_THRESHOLD {
+                let mut encoder = flate2::write::GzEncoder::new(Vec::new(), flate2::Compression::default());
+                encoder.write_all(&data)?;
+                builder = builder
+                    .header("Content-Encoding", "gzip")
+                    .body(encoder.finish()?);
+            } else {
+                builder = builder.body(data);
+            }
+        }
+
+        if let Some(timeout) = options.timeout.or(self.timeout) {
+            builder = builder
+                .timeout(timeout)
+                .header("Rpc-Timeout", timeout.as_millis().to_string());
         }
 
         if self.auth_token.is_some() {
@@ -68,25 +146,46 @@ var call = `    // call implementation.
         let resp = builder.send().await?;
 
         let status_code = resp.status();
+        let is_json = resp
+            .headers()
+            .get("Content-Type")
+            .map_or(false, |v| v == "application/json");
+        let is_gzip = resp
+            .headers()
+            .get("Content-Encoding")
+            .map_or(false, |v| v == "gzip");
+
+        let request_id = resp
+            .headers()
+            .get("X-Request-Id")
+            .and_then(|v| v.to_str().ok())
+            .map(String::from);
+
+        let mut body = resp.bytes().await?;
+        if is_gzip {
+            let mut data = Vec::new();
+            flate2::read::GzDecoder::new(&body[..]).read_to_end(&mut data)?;
+            body = bytes::Bytes::from(data);
+        }
+
         if status_code.as_u16() > 300 {
             let mut e = ClientError {
                 ..Default::default()
             };
 
-            if let Some(content_type) = resp.headers().get("Content-Type") {
-                if content_type == "application/json" {
-                    let body = resp.bytes().await?;
-                    e = serde_json::from_slice::<ClientError>(&body)?;
-                }
+            if is_json {
+                e = serde_json::from_slice::<ClientError>(&body)?;
             }
 
+            if e.request_id.is_none() {
+                e.request_id = request_id;
+            }
             e.status_code = status_code.as_u16();
             e.status = status_code.canonical_reason().unwrap_or_default().into();
 
             return Err(e);
         }
 
-        let body = resp.bytes().await?;
         return Ok(body);
     }
 `
@@ -101,6 +200,7 @@ func Generate(w io.Writer, s *schema.Schema) error {
 	out(w, "  client: reqwest::Client,\n")
 	out(w, "  endpoint: String,\n")
 	out(w, "  auth_token: Option<String>,\n")
+	out(w, "  timeout: Option<std::time::Duration>,\n")
 	out(w, "}\n\n")
 
 	out(w, "impl Client {\n\n")
@@ -109,32 +209,55 @@ func Generate(w io.Writer, s *schema.Schema) error {
 	out(w, "    Client {\n")
 	out(w, "      client: client,\n")
 	out(w, "      endpoint: endpoint.to_string(), \n")
-	out(w, "      auth_token: auth_token\n")
+	out(w, "      auth_token: auth_token,\n")
+	out(w, "      timeout: None\n")
 	out(w, "    }\n")
 	out(w, "  }\n\n")
 
+	out(w, "  // with_timeout returns the client with a timeout for calls, which is sent in the\n")
+	out(w, "  // Rpc-Timeout header so the server stops work the client no longer awaits.\n")
+	out(w, "  pub fn with_timeout(mut self, timeout: std::time::Duration) -> Client {\n")
+	out(w, "    self.timeout = Some(timeout);\n")
+	out(w, "    self\n")
+	out(w, "  }\n\n")
+
 	for _, m := range s.Methods {
+		// streaming methods are not supported by this client
+		if m.Stream {
+			continue
+		}
+
 		name := format.GoName(m.Name)
 		rname := format.RustName(m.Name)
-		out(w, "  // %s\n", m.Description)
-		out(w, "  pub async fn %s(&self", rname)
 
-		// input arg
-		if len(m.Inputs) > 0 {
-			out(w, ", input: &%sInput", name)
+		// output type
+		output := "()"
+		if len(m.Outputs) > 0 {
+			output = name + "Output"
 		}
-		out(w, ") ")
 
-		// output arg
-		if len(m.Outputs) > 0 {
-			out(w, "-> Result<%sOutput, ClientError> {\n", name)
+		// method with the default options
+		out(w, "  // %s\n", m.Description)
+		if len(m.Scopes) > 0 {
+			out(w, "  //\n")
+			out(w, "  // %s\n", format.Scopes(m.Scopes))
+		}
+		if len(m.Inputs) > 0 {
+			out(w, "  pub async fn %s(&self, input: &%sInput) -> Result<%s, ClientError> {\n", rname, name, output)
+			out(w, "    self.%s_with(input, &CallOptions::default()).await\n", rname)
 		} else {
-			out(w, "-> Result<(), ClientError> {\n")
+			out(w, "  pub async fn %s(&self) -> Result<%s, ClientError> {\n", rname, output)
+			out(w, "    self.%s_with(&CallOptions::default()).await\n", rname)
 		}
+		out(w, "  }\n\n")
 
-		// Serialize any inputs
+		// method with options
+		out(w, "  // %s_with calls %s with options, such as a timeout for this call.\n", rname, rname)
 		if len(m.Inputs) > 0 {
+			out(w, "  pub async fn %s_with(&self, input: &%sInput, options: &CallOptions) -> Result<%s, ClientError> {\n", rname, name, output)
 			out(w, "    let json = serde_json::to_vec(input)?;\n")
+		} else {
+			out(w, "  pub async fn %s_with(&self, options: &CallOptions) -> Result<%s, ClientError> {\n", rname, output)
 		}
 
 		if len(m.Outputs) > 0 {
@@ -150,7 +273,7 @@ func Generate(w io.Writer, s *schema.Schema) error {
 			out(w, "None")
 		}
 
-		out(w, ").await?;\n")
+		out(w, ", options).await?;\n")
 		if len(m.Outputs) > 0 {
 			out(w, "    let output: %sOutput = serde_json::from_slice(&res)?;\n", name)
 			out(w, "    return Ok(output)\n")
@@ -166,7 +289,52 @@ func Generate(w io.Writer, s *schema.Schema) error {
 
 	out(w, "}\n\n")
 
+	out(w, "%s", options)
+
 	out(w, "\n%s\n", error_handling)
 
+	out(w, "%s", compression)
+
+	writeErrors(w, s)
+
 	return nil
 }
+
+// writeErrors writes the typed errors declared in the schema to w.
+func writeErrors(w io.Writer, s *schema.Schema) {
+	out := fmt.Fprintf
+	errors := s.ErrorsSlice()
+
+	out(w, "\n// TypedError is an error declared in the schema.\n")
+	out(w, "#[derive(Debug, Clone)]\n")
+	out(w, "pub enum TypedError {\n")
+	for _, e := range errors {
+		name := format.GoName(e.Name)
+		out(w, "    // %s %s\n", name, e.Description)
+		if len(e.Details) > 0 {
+			out(w, "    %s(%sErrorDetails),\n", name, name)
+		} else {
+			out(w, "    %s,\n", name)
+		}
+	}
+	out(w, "}\n\n")
+
+	out(w, "impl ClientError {\n")
+	out(w, "    // typed_error returns the typed error declared in the schema, if any.\n")
+	out(w, "    pub fn typed_error(&self) -> Option<TypedError> {\n")
+	out(w, "        match self.err_type.as_deref() {\n")
+	for _, e := range errors {
+		name := format.GoName(e.Name)
+		if len(e.Details) > 0 {
+			out(w, "            Some(%q) => serde_json::from_value(self.details.clone())\n", e.Name)
+			out(w, "                .ok()\n")
+			out(w, "                .map(TypedError::%s),\n", name)
+		} else {
+			out(w, "            Some(%q) => Some(TypedError::%s),\n", e.Name, name)
+		}
+	}
+	out(w, "            _ => None,\n")
+	out(w, "        }\n")
+	out(w, "    }\n")
+	out(w, "}\n")
+}
@@ -1 +1,23 @@
 package rustclient_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tj/assert"
+	"github.com/tj/go-fixture"
+
+	"github.com/apex/rpc/generators/rustclient"
+	"github.com/apex/rpc/schema"
+)
+
+func TestGenerate(t *testing.T) {
+	schema, err := schema.Load("../../examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	var act bytes.Buffer
+	err = rustclient.Generate(&act, schema)
+	assert.NoError(t, err, "generating")
+
+	fixture.Assert(t, "todo_client.rs", act.Bytes())
+}
@@ -0,0 +1,265 @@
+// Client is the API client.
+#[derive(Debug, Clone)]
+pub struct Client {
+  client: reqwest::Client,
+  endpoint: String,
+  auth_token: Option<String>,
+  timeout: Option<std::time::Duration>,
+}
+
+impl Client {
+
+  pub fn new(client: reqwest::Client, endpoint: &str, auth_token: Option<String>) -> Client{
+    Client {
+      client: client,
+      endpoint: endpoint.to_string(), 
+      auth_token: auth_token,
+      timeout: None
+    }
+  }
+
+  // with_timeout returns the client with a timeout for calls, which is sent in the
+  // Rpc-Timeout header so the server stops work the client no longer awaits.
+  pub fn with_timeout(mut self, timeout: std::time::Duration) -> Client {
+    self.timeout = Some(timeout);
+    self
+  }
+
+  // adds an item to the list.
+  pub async fn add_item(&self, input: &AddItemInput) -> Result<(), ClientError> {
+    self.add_item_with(input, &CallOptions::default()).await
+  }
+
+  // add_item_with calls add_item with options, such as a timeout for this call.
+  pub async fn add_item_with(&self, input: &AddItemInput, options: &CallOptions) -> Result<(), ClientError> {
+    let json = serde_json::to_vec(input)?;
+    self.call("add_item", Some(json), options).await?;
+    Ok(())
+  }
+
+  // returns all items in the list.
+  pub async fn get_items(&self) -> Result<GetItemsOutput, ClientError> {
+    self.get_items_with(&CallOptions::default()).await
+  }
+
+  // get_items_with calls get_items with options, such as a timeout for this call.
+  pub async fn get_items_with(&self, options: &CallOptions) -> Result<GetItemsOutput, ClientError> {
+    let res: bytes::Bytes = self.call("get_items", None, options).await?;
+    let output: GetItemsOutput = serde_json::from_slice(&res)?;
+    return Ok(output)
+  }
+
+  // removes an item from the to-do list.
+  //
+  // Requires the items:write scope.
+  pub async fn remove_item(&self, input: &RemoveItemInput) -> Result<RemoveItemOutput, ClientError> {
+    self.remove_item_with(input, &CallOptions::default()).await
+  }
+
+  // remove_item_with calls remove_item with options, such as a timeout for this call.
+  pub async fn remove_item_with(&self, input: &RemoveItemInput, options: &CallOptions) -> Result<RemoveItemOutput, ClientError> {
+    let json = serde_json::to_vec(input)?;
+    let res: bytes::Bytes = self.call("remove_item", Some(json), options).await?;
+    let output: RemoveItemOutput = serde_json::from_slice(&res)?;
+    return Ok(output)
+  }
+
+
+    // call implementation.
+    async fn call(
+        &self,
+        method: &str,
+        input: Option<Vec<u8>>,
+        options: &CallOptions,
+    ) -> Result<bytes::Bytes, ClientError> {
+        use std::io::{Read, Write};
+
+        let uri = format!("{}/{}", self.endpoint, method);
+
+        let mut builder = self
+            .client
+            .post(&uri)
+            .header("Content-Type", "application/json")
+            .header("Accept-Encoding", "gzip");
+
+        if let Some(data) = input {
+            if data.len() >= COMPRESSION_THRESHOLD {
+                let mut encoder = flate2::write::GzEncoder::new(Vec::new(), flate2::Compression::default());
+                encoder.write_all(&data)?;
+                builder = builder
+                    .header("Content-Encoding", "gzip")
+                    .body(encoder.finish()?);
+            } else {
+                builder = builder.body(data);
+            }
+        }
+
+        if let Some(timeout) = options.timeout.or(self.timeout) {
+            builder = builder
+                .timeout(timeout)
+                .header("Rpc-Timeout", timeout.as_millis().to_string());
+        }
+
+        if self.auth_token.is_some() {
+            builder = builder.header(
+                "Authorization",
+                format!("Bearer {}", &self.auth_token.as_ref().unwrap())
+            );
+        }
+
+        let resp = builder.send().await?;
+
+        let status_code = resp.status();
+        let is_json = resp
+            .headers()
+            .get("Content-Type")
+            .map_or(false, |v| v == "application/json");
+        let is_gzip = resp
+            .headers()
+            .get("Content-Encoding")
+            .map_or(false, |v| v == "gzip");
+
+        let request_id = resp
+            .headers()
+            .get("X-Request-Id")
+            .and_then(|v| v.to_str().ok())
+            .map(String::from);
+
+        let mut body = resp.bytes().await?;
+        if is_gzip {
+            let mut data = Vec::new();
+            flate2::read::GzDecoder::new(&body[..]).read_to_end(&mut data)?;
+            body = bytes::Bytes::from(data);
+        }
+
+        if status_code.as_u16() > 300 {
+            let mut e = ClientError {
+                ..Default::default()
+            };
+
+            if is_json {
+                e = serde_json::from_slice::<ClientError>(&body)?;
+            }
+
+            if e.request_id.is_none() {
+                e.request_id = request_id;
+            }
+            e.status_code = status_code.as_u16();
+            e.status = status_code.canonical_reason().unwrap_or_default().into();
+
+            return Err(e);
+        }
+
+        return Ok(body);
+    }
+
+}
+
+// CallOptions are the options of a call. The timeout overrides that of the client,
+// and is sent in the Rpc-Timeout header so the server stops work the client no
+// longer awaits.
+#[derive(Debug, Clone, Default)]
+pub struct CallOptions {
+    pub timeout: Option<std::time::Duration>,
+}
+
+// Error is an error returned by the client.
+#[derive(Serialize, Deserialize, Debug, Clone, Default)]
+pub struct ClientError {
+    #[serde(default)]
+    status: String,
+    #[serde(default)]
+    status_code: u16,
+    #[serde(rename = "type")]
+    err_type: Option<String>,
+    message: Option<String>,
+    #[serde(default)]
+    fields: Vec<FieldError>,
+    #[serde(default)]
+    details: serde_json::Value,
+    request_id: Option<String>,
+}
+
+impl ClientError {
+    // fields returns the field validation errors.
+    pub fn fields(&self) -> &[FieldError] {
+        &self.fields
+    }
+
+    // request_id returns the id of the request, if any.
+    pub fn request_id(&self) -> Option<&str> {
+        self.request_id.as_deref()
+    }
+}
+
+// FieldError is a field validation error, where path is a JSON pointer to the field.
+#[derive(Serialize, Deserialize, Debug, Clone, Default)]
+pub struct FieldError {
+    pub path: String,
+    pub field: String,
+    pub message: String,
+}
+
+impl From<serde_json::error::Error> for ClientError {
+    fn from(err: serde_json::error::Error) -> ClientError {
+        ClientError {
+            status: "Internal Server Error".into(),
+            status_code: 500,
+            err_type: Some("serde".into()),
+            message: Some(err.to_string()),
+            fields: Vec::new(),
+            details: serde_json::Value::Null,
+            request_id: None,
+        }
+    }
+}
+
+impl From<std::io::Error> for ClientError {
+    fn from(err: std::io::Error) -> ClientError {
+        ClientError {
+            status: "Internal Server Error".into(),
+            status_code: 500,
+            err_type: Some("io".into()),
+            message: Some(err.to_string()),
+            fields: Vec::new(),
+            details: serde_json::Value::Null,
+            request_id: None,
+        }
+    }
+}
+
+impl From<reqwest::Error> for ClientError {
+    fn from(err: reqwest::Error) -> ClientError {
+        ClientError {
+            status: "Internal Server Error".into(),
+            status_code: 500,
+            err_type: Some("reqwest".into()),
+            message: Some(err.to_string()),
+            fields: Vec::new(),
+            details: serde_json::Value::Null,
+            request_id: None,
+        }
+    }
+}
+
+// COMPRESSION_THRESHOLD is the minimum size in bytes of a request body before it is compressed.
+const COMPRESSION_THRESHOLD: usize = 1024;
+
+// TypedError is an error declared in the schema.
+#[derive(Debug, Clone)]
+pub enum TypedError {
+    // ItemNotFound is returned when the item does not exist.
+    ItemNotFound(ItemNotFoundErrorDetails),
+}
+
+impl ClientError {
+    // typed_error returns the typed error declared in the schema, if any.
+    pub fn typed_error(&self) -> Option<TypedError> {
+        match self.err_type.as_deref() {
+            Some("item_not_found") => serde_json::from_value(self.details.clone())
+                .ok()
+                .map(TypedError::ItemNotFound),
+            _ => None,
+        }
+    }
+}
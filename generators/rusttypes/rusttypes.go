@@ -28,6 +28,20 @@ func Generate(w io.Writer, s *schema.Schema) error {
 		out(w, "}\n\n")
 	}
 
+	// error details
+	for _, e := range s.ErrorsSlice() {
+		if len(e.Details) == 0 {
+			continue
+		}
+
+		name := format.GoName(e.Name) + "Error"
+		out(w, "// %sDetails is the details of %s.\n", name, name)
+		out(w, "#[derive(Serialize, Deserialize, Debug, Clone)]\n")
+		out(w, "pub struct %sDetails {\n", name)
+		writeFields(w, s, e.Details)
+		out(w, "}\n\n")
+	}
+
 	// methods
 	for _, m := range s.Methods {
 		name := format.GoName(m.Name)
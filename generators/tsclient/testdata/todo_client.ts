@@ -6,58 +6,517 @@ const fetch = (typeof window == 'undefined' || window.fetch == null)
   : window.fetch
 
 /**
- * ClientError is an API client error providing the HTTP status code and error type.
+ * FieldError is a field validation error, where path is a JSON pointer to the field.
  */
 
-class ClientError extends Error {
+export interface FieldError {
+  path: string
+  field: string
+  message: string
+}
+
+/**
+ * ClientError is an API client error providing the HTTP status code, error type and field errors.
+ */
+
+export class ClientError extends Error {
   status: number;
   type?: string;
+  fields: FieldError[];
+  retryable?: boolean;
+  retryAfter?: number;
+  requestId?: string;
 
-  constructor(status: number, message?: string, type?: string) {
+  constructor(status: number, message?: string, type?: string, fields?: FieldError[]) {
     super(message)
     this.status = status
     this.type = type
+    this.fields = fields || []
   }
 }
 
+/**
+ * Codec is used for encoding request bodies and decoding response bodies.
+ */
+
+export interface Codec {
+  contentType: string
+  encode(value: any): Uint8Array
+  decode(data: Uint8Array): any
+}
+
+/**
+ * CallOptions are the options of a call. The timeout in milliseconds aborts
+ * the call, and is sent in the Rpc-Timeout header so the server stops work
+ * the client no longer awaits. The W3C traceparent is sent with the call,
+ * or a new trace is started.
+ */
+
+export interface CallOptions {
+  signal?: AbortSignal
+  timeout?: number
+  traceparent?: string
+}
+
+/**
+ * Minimum size of a request body before it is compressed.
+ */
+
+const compressionThreshold = 1024
+
 /**
  * Call method with params via a POST request.
  */
 
-async function call(url: string, method: string, authToken?: string, params?: any): Promise<string> {
+async function call(url: string, method: string, authToken?: string, params?: any, codec?: Codec, extraHeaders?: Record<string, string>, options?: CallOptions): Promise<any> {
+  const contentType = codec ? codec.contentType : 'application/json'
   const headers: Record<string, string> = {
-    'Content-Type': 'application/json'
+    ...extraHeaders,
+    'Content-Type': contentType,
+    'Accept': contentType
   }
   
   if (authToken != null) {
     headers['Authorization'] = `Bearer ${authToken}`
   }
-  
-  const res = await fetch(url + '/' + method, {
-    method: 'POST',
-    body: JSON.stringify(params),
-    headers
-  })
 
-  // we have an error, try to parse a well-formed json
-  // error response, otherwise default to status code
-  if (res.status >= 300) {
-    let err
+  let body: any = codec ? codec.encode(params) : JSON.stringify(params)
+  if (body != null && body.length >= compressionThreshold && typeof CompressionStream != 'undefined') {
+    body = await compress(body)
+    headers['Content-Encoding'] = 'gzip'
+  }
+
+  headers['traceparent'] = (options && options.traceparent) || newTraceparent()
+
+  const { signal, clear } = abortable(headers, options)
+  try {
+    const res = await fetch(url + '/' + method, {
+      method: 'POST',
+      body,
+      headers,
+      signal
+    })
+
+    if (res.status >= 300) {
+      throw await error(res, codec)
+    }
+
+    return await decode(res, codec)
+  } finally {
+    clear()
+  }
+}
+
+/**
+ * Return the signal aborting a request with the given options,
+ * setting the Rpc-Timeout header when a timeout is provided.
+ */
+
+function abortable(headers: Record<string, string>, options: CallOptions = {}): { signal?: AbortSignal, clear(): void } {
+  const { signal, timeout } = options
+  if (timeout == null) {
+    return { signal, clear() {} }
+  }
+
+  headers['Rpc-Timeout'] = String(Math.max(0, Math.floor(timeout)))
+
+  const controller = new AbortController()
+  const abort = () => controller.abort()
+  const timer = setTimeout(abort, timeout)
+  if (signal) {
+    signal.aborted ? abort() : signal.addEventListener('abort', abort)
+  }
+
+  return {
+    signal: controller.signal,
+    clear() {
+      clearTimeout(timer)
+      if (signal) {
+        signal.removeEventListener('abort', abort)
+      }
+    }
+  }
+}
+
+/**
+ * Call a safe method with params via a GET request, sending them as query-string
+ * parameters so responses may be cached.
+ */
+
+async function get(url: string, method: string, authToken?: string, params?: any, codec?: Codec, options?: CallOptions): Promise<any> {
+  const headers: Record<string, string> = {
+    'Accept': codec ? codec.contentType : 'application/json'
+  }
+
+  if (authToken != null) {
+    headers['Authorization'] = `Bearer ${authToken}`
+  }
+
+  headers['traceparent'] = (options && options.traceparent) || newTraceparent()
+
+  const { signal, clear } = abortable(headers, options)
+  try {
+    const res = await fetch(url + '/' + method + query(params), {
+      method: 'GET',
+      headers,
+      signal
+    })
+
+    if (res.status >= 300) {
+      throw await error(res, codec)
+    }
+
+    return await decode(res, codec)
+  } finally {
+    clear()
+  }
+}
+
+/**
+ * Return the query-string of params, where arrays are repeated
+ * parameters and objects are JSON encoded.
+ */
+
+function query(params?: any): string {
+  const q = new URLSearchParams()
+  for (const name of Object.keys(params || {})) {
+    const value = params[name]
+    if (value == null) {
+      continue
+    }
+    for (const v of Array.isArray(value) ? value : [value]) {
+      q.append(name, v instanceof Date ? v.toISOString() : typeof v == 'object' ? JSON.stringify(v) : String(v))
+    }
+  }
+  const s = q.toString()
+  return s ? '?' + s : ''
+}
+
+/**
+ * Call an idempotent method with an Idempotency-Key, retrying up to retries times
+ * with the same key after network errors and retryable responses.
+ */
+
+function callIdempotent(url: string, method: string, authToken?: string, params?: any, codec?: Codec, retries: number = 0, options: CallOptions = {}): Promise<any> {
+  const headers = { 'Idempotency-Key': newKey() }
+  return retry(retries, options, options => call(url, method, authToken, params, codec, headers, options))
+}
+
+/**
+ * Call a safe method via a GET request, retrying up to retries times
+ * after network errors and retryable responses.
+ */
+
+function callSafe(url: string, method: string, authToken?: string, params?: any, codec?: Codec, retries: number = 0, options: CallOptions = {}): Promise<any> {
+  return retry(retries, options, options => get(url, method, authToken, params, codec, options))
+}
+
+/**
+ * Call fn, retrying up to retries times after network errors and retryable
+ * responses with the same traceparent. The timeout of the options applies
+ * to all of the attempts.
+ */
+
+async function retry(retries: number, options: CallOptions, fn: (options: CallOptions) => Promise<any>): Promise<any> {
+  const { signal, timeout, traceparent = newTraceparent() } = options
+  const deadline = timeout == null ? undefined : Date.now() + timeout
+
+  for (let attempt = 0; ; attempt++) {
     try {
-      const { type, message } = await res.json()
-      err = new ClientError(res.status, message, type)
-    } catch {
-      err = new ClientError(res.status, res.statusText)
+      const remaining = deadline == null ? undefined : deadline - Date.now()
+      return await fn({ signal, timeout: remaining, traceparent })
+    } catch (err) {
+      const done = (signal && signal.aborted) || (deadline != null && Date.now() >= deadline)
+      if (attempt >= retries || done || !retryable(err)) {
+        throw err
+      }
+      await sleep(backoff(attempt, err))
     }
-    throw err
   }
+}
 
-  return res.text()
+/**
+ * Return a new random idempotency key.
+ */
+
+function newKey(): string {
+  if (typeof crypto != 'undefined' && crypto.randomUUID) {
+    return crypto.randomUUID()
+  }
+  return Math.random().toString(36).slice(2) + Date.now().toString(36)
+}
+
+/**
+ * Return a new W3C traceparent starting a sampled trace.
+ */
+
+function newTraceparent(): string {
+  return `00-${randomHex(16)}-${randomHex(8)}-01`
+}
+
+/**
+ * Return n random bytes, hex encoded.
+ */
+
+function randomHex(n: number): string {
+  const bytes = new Uint8Array(n)
+  if (typeof crypto != 'undefined' && crypto.getRandomValues) {
+    crypto.getRandomValues(bytes)
+  } else {
+    for (let i = 0; i < n; i++) {
+      bytes[i] = Math.floor(Math.random() * 256)
+    }
+  }
+  return Array.from(bytes, b => b.toString(16).padStart(2, '0')).join('')
 }
 
+/**
+ * Return true if err is a network error or a retryable response.
+ */
+
+function retryable(err: any): boolean {
+  if (!(err instanceof ClientError)) {
+    return true
+  }
+  return !!err.retryable || [502, 503, 504].indexOf(err.status) >= 0
+}
+
+/**
+ * Return the delay in milliseconds before a retry, using the retry-after hint of err when provided.
+ */
+
+function backoff(attempt: number, err: any): number {
+  if (err instanceof ClientError && err.retryAfter) {
+    return err.retryAfter * 1000
+  }
+  return 100 * Math.pow(2, attempt)
+}
+
+/**
+ * Sleep for ms milliseconds.
+ */
+
+function sleep(ms: number): Promise<void> {
+  return new Promise(resolve => setTimeout(resolve, ms))
+}
+
+/**
+ * Stream the outputs of method with params via a POST request, throwing when
+ * the stream is truncated before its end frame.
+ */
+
+async function* stream(url: string, method: string, authToken?: string, params?: any, options?: CallOptions): AsyncIterableIterator<any> {
+  const headers: Record<string, string> = {
+    'Content-Type': 'application/json',
+    'Accept': 'application/x-ndjson'
+  }
+
+  if (authToken != null) {
+    headers['Authorization'] = `Bearer ${authToken}`
+  }
+
+  headers['traceparent'] = (options && options.traceparent) || newTraceparent()
+
+  const { signal, clear } = abortable(headers, options)
+  try {
+    const res = await fetch(url + '/' + method, {
+      method: 'POST',
+      body: JSON.stringify(params),
+      headers,
+      signal
+    })
+
+    if (res.status >= 300) {
+      throw await error(res)
+    }
+
+    const utf8 = new TextDecoder()
+    let buffer = ''
+
+    for await (const chunk of chunks(res.body)) {
+      buffer += utf8.decode(chunk, { stream: true })
+
+      let i
+      while ((i = buffer.indexOf('\n')) >= 0) {
+        const line = buffer.slice(0, i).trim()
+        buffer = buffer.slice(i + 1)
+        if (!line) {
+          continue
+        }
+
+        const f = frame(line)
+        if (f.end) {
+          return
+        }
+        yield f.data
+      }
+    }
+
+    throw new ClientError(0, 'Stream ended without an end frame')
+  } finally {
+    clear()
+  }
+}
+
+/**
+ * Iterate the chunks of a response body, canceling it when iteration stops early.
+ */
+
+async function* chunks(body: any): AsyncIterableIterator<Uint8Array> {
+  // node streams are async iterable
+  if (!body.getReader) {
+    yield* body
+    return
+  }
+
+  const reader = body.getReader()
+  try {
+    while (true) {
+      const { done, value } = await reader.read()
+      if (done) {
+        return
+      }
+      yield value
+    }
+  } finally {
+    reader.cancel()
+  }
+}
+
+/**
+ * Decode a stream frame holding data or the end of the stream, throwing its error if any.
+ */
+
+function frame(line: string): { data?: any, end?: boolean } {
+  const f = JSON.parse(line, decoder)
+  if (f.error) {
+    throw fromBody(0, f.error)
+  }
+  return f
+}
+
+/**
+ * BatchCall is a call in a batch, settled once the batch is sent.
+ */
+
+interface BatchCall {
+  method: string
+  input?: any
+  resolve(value: any): void
+  reject(err: any): void
+}
+
+/**
+ * Settle the batched calls with their results.
+ */
+
+function settle(calls: BatchCall[], results: any[]) {
+  calls.forEach((call, i) => {
+    const res = results[i]
+    if (res == null) {
+      call.reject(new ClientError(0, 'Missing batch result'))
+    } else if (res.error) {
+      call.reject(fromBody(res.status, res.error))
+    } else {
+      call.resolve(res.result)
+    }
+  })
+}
+
+/**
+ * Create an error from a well-formed error response,
+ * otherwise default to the status code.
+ */
+
+async function error(res: any, codec?: Codec): Promise<ClientError> {
+  let err: ClientError
+  try {
+    err = fromBody(res.status, await decode(res, codec))
+  } catch {
+    err = new ClientError(res.status, res.statusText)
+  }
+  err.requestId = err.requestId || res.headers.get('X-Request-Id') || undefined
+  return err
+}
+
+/**
+ * Create an error from the body of an error response.
+ */
+
+function fromBody(status: number, body: any): ClientError {
+  const { type, message, fields, details, retryable, retry_after, request_id } = body
+  const err = newError(status, message, type, fields, details)
+  err.retryable = retryable
+  err.retryAfter = retry_after
+  err.requestId = request_id
+  return err
+}
+
+/**
+ * Compress the request body with gzip. Response bodies are
+ * decompressed by fetch according to their Content-Encoding.
+ */
+
+async function compress(body: string | Uint8Array): Promise<Uint8Array> {
+  const stream = new Blob([body]).stream().pipeThrough(new CompressionStream('gzip'))
+  return new Uint8Array(await new Response(stream).arrayBuffer())
+}
+
+/**
+ * Decode the response body using the codec when it matches
+ * the response Content-Type, otherwise as JSON.
+ */
+
+async function decode(res: any, codec?: Codec): Promise<any> {
+  if (res.status == 204) {
+    return
+  }
+
+  if (codec && res.headers.get('Content-Type') == codec.contentType) {
+    return codec.decode(new Uint8Array(await res.arrayBuffer()))
+  }
+
+  return JSON.parse(await res.text(), decoder)
+}
+
+/**
+ * ItemNotFoundError is returned when the item does not exist.
+ */
+
+export class ItemNotFoundError extends ClientError {
+  details?: ItemNotFoundErrorDetails;
+
+  constructor(status: number, message?: string, details?: ItemNotFoundErrorDetails) {
+    super(status, message, 'item_not_found')
+    this.details = details
+  }
+}
+
+/**
+ * Create an error, typed when declared in the schema.
+ */
+
+function newError(status: number, message?: string, type?: string, fields?: FieldError[], details?: any): ClientError {
+  switch (type) {
+    case 'item_not_found':
+      return new ItemNotFoundError(status, message, details)
+    default:
+      return new ClientError(status, message, type, fields)
+  }
+}
 
 const reISO8601 = /(\d{4}-[01]\d-[0-3]\dT[0-2]\d:[0-5]\d:[0-5]\d\.\d+([+-][0-2]\d:[0-5]\d|Z))|(\d{4}-[01]\d-[0-3]\dT[0-2]\d:[0-5]\d:[0-5]\d([+-][0-2]\d:[0-5]\d|Z))|(\d{4}-[01]\d-[0-3]\dT[0-2]\d:[0-5]\d([+-][0-2]\d:[0-5]\d|Z))/
 
+/**
+ * Decoder is used as the reviver parameter when decoding JSON responses.
+ */
+
+function decoder(key: any, value: any) {
+  return typeof value == 'string' && reISO8601.test(value)
+    ? new Date(value)
+    : value
+}
+
 /**
  * Client is the API client.
  */
@@ -66,52 +525,142 @@ export class Client {
 
   private url: string
   private authToken?: string
+  private codec?: Codec
+  private retries: number
 
   /**
-   * Initialize.
+   * Initialize, retries is the number of times calls of idempotent and safe methods are
+   * retried after network errors or retryable responses, defaulting to none.
    */
 
-  constructor(params: { url: string, authToken?: string }) {
+  constructor(params: { url: string, authToken?: string, codec?: Codec, retries?: number }) {
     this.url = params.url
     this.authToken = params.authToken
+    this.codec = params.codec
+    this.retries = params.retries || 0
   }
 
   /**
-   * Decoder is used as the reviver parameter when decoding responses.
+   * newBatch returns a new batch of calls sent in a single request.
    */
 
-  private decoder(key: any, value: any) {
-    return typeof value == 'string' && reISO8601.test(value)
-      ? new Date(value)
-      : value
+  newBatch(): Batch {
+    return new Batch({ url: this.url, authToken: this.authToken, codec: this.codec })
   }
 
   /**
    * addItem: adds an item to the list.
    */
 
-  async addItem(params: AddItemInput) {
-    await call(this.url, 'add_item', this.authToken, params)
+  async addItem(params: AddItemInput, options?: CallOptions) {
+    await callIdempotent(this.url, 'add_item', this.authToken, params, this.codec, this.retries, options)
   }
 
   /**
    * getItems: returns all items in the list.
    */
 
-  async getItems(): Promise<GetItemsOutput> {
-    let res = await call(this.url, 'get_items', this.authToken)
-    let out: GetItemsOutput = JSON.parse(res, this.decoder)
+  async getItems(options?: CallOptions): Promise<GetItemsOutput> {
+    let out: GetItemsOutput = await callSafe(this.url, 'get_items', this.authToken, undefined, this.codec, this.retries, options)
     return out
   }
 
   /**
    * removeItem: removes an item from the to-do list.
+   *
+   * Requires the items:write scope.
    */
 
-  async removeItem(params: RemoveItemInput): Promise<RemoveItemOutput> {
-    let res = await call(this.url, 'remove_item', this.authToken, params)
-    let out: RemoveItemOutput = JSON.parse(res, this.decoder)
+  async removeItem(params: RemoveItemInput, options?: CallOptions): Promise<RemoveItemOutput> {
+    let out: RemoveItemOutput = await call(this.url, 'remove_item', this.authToken, params, this.codec, undefined, options)
     return out
   }
 
+  /**
+   * watchItems: streams items as they are added to the list.
+   */
+
+  async *watchItems(options?: CallOptions): AsyncIterableIterator<WatchItemsOutput> {
+    yield* stream(this.url, 'watch_items', this.authToken, undefined, options)
+  }
+
+}
+
+/**
+ * Batch is a batch of calls sent in a single request, the promise
+ * returned by each call is settled once the batch is sent.
+ */
+
+export class Batch {
+
+  private url: string
+  private authToken?: string
+  private codec?: Codec
+  private calls: BatchCall[] = []
+
+  /**
+   * Initialize.
+   */
+
+  constructor(params: { url: string, authToken?: string, codec?: Codec }) {
+    this.url = params.url
+    this.authToken = params.authToken
+    this.codec = params.codec
+  }
+
+  /**
+   * send the batch, rejecting the calls and throwing when the request fails.
+   */
+
+  async send(options?: CallOptions) {
+    const calls = this.calls
+    this.calls = []
+
+    try {
+      const params = calls.map(({ method, input }) => ({ method, input }))
+      settle(calls, await call(this.url, '_batch', this.authToken, params, this.codec, undefined, options))
+    } catch (err) {
+      calls.forEach(call => call.reject(err))
+      throw err
+    }
+  }
+
+  /**
+   * add a call of method to the batch.
+   */
+
+  private add(method: string, input?: any): Promise<any> {
+    const promise = new Promise((resolve, reject) => {
+      this.calls.push({ method, input, resolve, reject })
+    })
+    // rejections are also reported by send()
+    promise.catch(() => {})
+    return promise
+  }
+
+  /**
+   * addItem: adds an item to the list.
+   */
+
+  addItem(params: AddItemInput): Promise<void> {
+    return this.add('add_item', params)
+  }
+
+  /**
+   * getItems: returns all items in the list.
+   */
+
+  getItems(): Promise<GetItemsOutput> {
+    return this.add('get_items')
+  }
+
+  /**
+   * removeItem: removes an item from the to-do list.
+   *
+   * Requires the items:write scope.
+   */
+
+  removeItem(params: RemoveItemInput): Promise<RemoveItemOutput> {
+    return this.add('remove_item', params)
+  }
 }
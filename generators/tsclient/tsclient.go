@@ -17,53 +17,477 @@ const fetch = (typeof window == 'undefined' || window.fetch == null)
 `
 
 var call = `/**
- * ClientError is an API client error providing the HTTP status code and error type.
+ * FieldError is a field validation error, where path is a JSON pointer to the field.
  */
 
-class ClientError extends Error {
+export interface FieldError {
+  path: string
+  field: string
+  message: string
+}
+
+/**
+ * ClientError is an API client error providing the HTTP status code, error type and field errors.
+ */
+
+export class ClientError extends Error {
   status: number;
   type?: string;
+  fields: FieldError[];
+  retryable?: boolean;
+  retryAfter?: number;
+  requestId?: string;
 
-  constructor(status: number, message?: string, type?: string) {
+  constructor(status: number, message?: string, type?: string, fields?: FieldError[]) {
     super(message)
     this.status = status
     this.type = type
+    this.fields = fields || []
   }
 }
 
+/**
+ * Codec is used for encoding request bodies and decoding response bodies.
+ */
+
+export interface Codec {
+  contentType: string
+  encode(value: any): Uint8Array
+  decode(data: Uint8Array): any
+}
+
+/**
+ * CallOptions are the options of a call. The timeout in milliseconds aborts
+ * the call, and is sent in the Rpc-Timeout header so the server stops work
+ * the client no longer awaits. The W3C traceparent is sent with the call,
+ * or a new trace is started.
+ */
+
+export interface CallOptions {
+  signal?: AbortSignal
+  timeout?: number
+  traceparent?: string
+}
+
+/**
+ * Minimum size of a request body before it is compressed.
+ */
+
+const compressionThreshold = 1024
+
 /**
  * Call method with params via a POST request.
  */
 
-async function call(url: string, method: string, authToken?: string, params?: any): Promise<string> {
+async function call(url: string, method: string, authToken?: string, params?: any, codec?: Codec, extraHeaders?: Record<string, string>, options?: CallOptions): Promise<any> {
+  const contentType = codec ? codec.contentType : 'application/json'
   const headers: Record<string, string> = {
-    'Content-Type': 'application/json'
+    ...extraHeaders,
+    'Content-Type': contentType,
+    'Accept': contentType
   }
   
   if (authToken != null) {
     headers['Authorization'] = ` + "`Bearer ${authToken}`" + `
   }
-  
-  const res = await fetch(url + '/' + method, {
-    method: 'POST',
-    body: JSON.stringify(params),
-    headers
-  })
 
-  // we have an error, try to parse a well-formed json
-  // error response, otherwise default to status code
-  if (res.status >= 300) {
-    let err
+  let body: any = codec ? codec.encode(params) : JSON.stringify(params)
+  if (body != null && body.length >= compressionThreshold && typeof CompressionStream != 'undefined') {
+    body = await compress(body)
+    headers['Content-Encoding'] = 'gzip'
+  }
+
+  headers['traceparent'] = (options && options.traceparent) || newTraceparent()
+
+  const { signal, clear } = abortable(headers, options)
+  try {
+    const res = await fetch(url + '/' + method, {
+      method: 'POST',
+      body,
+      headers,
+      signal
+    })
+
+    if (res.status >= 300) {
+      throw await error(res, codec)
+    }
+
+    return await decode(res, codec)
+  } finally {
+    clear()
+  }
+}
+
+/**
+ * Return the signal aborting a request with the given options,
+ * setting the Rpc-Timeout header when a timeout is provided.
+ */
+
+function abortable(headers: Record<string, string>, options: CallOptions = {}): { signal?: AbortSignal, clear(): void } {
+  const { signal, timeout } = options
+  if (timeout == null) {
+    return { signal, clear() {} }
+  }
+
+  headers['Rpc-Timeout'] = String(Math.max(0, Math.floor(timeout)))
+
+  const controller = new AbortController()
+  const abort = () => controller.abort()
+  const timer = setTimeout(abort, timeout)
+  if (signal) {
+    signal.aborted ? abort() : signal.addEventListener('abort', abort)
+  }
+
+  return {
+    signal: controller.signal,
+    clear() {
+      clearTimeout(timer)
+      if (signal) {
+        signal.removeEventListener('abort', abort)
+      }
+    }
+  }
+}
+
+/**
+ * Call a safe method with params via a GET request, sending them as query-string
+ * parameters so responses may be cached.
+ */
+
+async function get(url: string, method: string, authToken?: string, params?: any, codec?: Codec, options?: CallOptions): Promise<any> {
+  const headers: Record<string, string> = {
+    'Accept': codec ? codec.contentType : 'application/json'
+  }
+
+  if (authToken != null) {
+    headers['Authorization'] = ` + "`Bearer ${authToken}`" + `
+  }
+
+  headers['traceparent'] = (options && options.traceparent) || newTraceparent()
+
+  const { signal, clear } = abortable(headers, options)
+  try {
+    const res = await fetch(url + '/' + method + query(params), {
+      method: 'GET',
+      headers,
+      signal
+    })
+
+    if (res.status >= 300) {
+      throw await error(res, codec)
+    }
+
+    return await decode(res, codec)
+  } finally {
+    clear()
+  }
+}
+
+/**
+ * Return the query-string of params, where arrays are repeated
+ * parameters and objects are JSON encoded.
+ */
+
+function query(params?: any): string {
+  const q = new URLSearchParams()
+  for (const name of Object.keys(params || {})) {
+    const value = params[name]
+    if (value == null) {
+      continue
+    }
+    for (const v of Array.isArray(value) ? value : [value]) {
+      q.append(name, v instanceof Date ? v.toISOString() : typeof v == 'object' ? JSON.stringify(v) : String(v))
+    }
+  }
+  const s = q.toString()
+  return s ? '?' + s : ''
+}
+
+/**
+ * Call an idempotent method with an Idempotency-Key, retrying up to retries times
+ * with the same key after network errors and retryable responses.
+ */
+
+function callIdempotent(url: string, method: string, authToken?: string, params?: any, codec?: Codec, retries: number = 0, options: CallOptions = {}): Promise<any> {
+  const headers = { 'Idempotency-Key': newKey() }
+  return retry(retries, options, options => call(url, method, authToken, params, codec, headers, options))
+}
+
+/**
+ * Call a safe method via a GET request, retrying up to retries times
+ * after network errors and retryable responses.
+ */
+
+function callSafe(url: string, method: string, authToken?: string, params?: any, codec?: Codec, retries: number = 0, options: CallOptions = {}): Promise<any> {
+  return retry(retries, options, options => get(url, method, authToken, params, codec, options))
+}
+
+/**
+ * Call fn, retrying up to retries times after network errors and retryable
+ * responses with the same traceparent. The timeout of the options applies
+ * to all of the attempts.
+ */
+
+async function retry(retries: number, options: CallOptions, fn: (options: CallOptions) => Promise<any>): Promise<any> {
+  const { signal, timeout, traceparent = newTraceparent() } = options
+  const deadline = timeout == null ? undefined : Date.now() + timeout
+
+  for (let attempt = 0; ; attempt++) {
     try {
-      const { type, message } = await res.json()
-      err = new ClientError(res.status, message, type)
-    } catch {
-      err = new ClientError(res.status, res.statusText)
+      const remaining = deadline == null ? undefined : deadline - Date.now()
+      return await fn({ signal, timeout: remaining, traceparent })
+    } catch (err) {
+      const done = (signal && signal.aborted) || (deadline != null && Date.now() >= deadline)
+      if (attempt >= retries || done || !retryable(err)) {
+        throw err
+      }
+      await sleep(backoff(attempt, err))
+    }
+  }
+}
+
+/**
+ * Return a new random idempotency key.
+ */
+
+function newKey(): string {
+  if (typeof crypto != 'undefined' && crypto.randomUUID) {
+    return crypto.randomUUID()
+  }
+  return Math.random().toString(36).slice(2) + Date.now().toString(36)
+}
+
+/**
+ * Return a new W3C traceparent starting a sampled trace.
+ */
+
+function newTraceparent(): string {
+  return ` + "`00-${randomHex(16)}-${randomHex(8)}-01`" + `
+}
+
+/**
+ * Return n random bytes, hex encoded.
+ */
+
+function randomHex(n: number): string {
+  const bytes = new Uint8Array(n)
+  if (typeof crypto != 'undefined' && crypto.getRandomValues) {
+    crypto.getRandomValues(bytes)
+  } else {
+    for (let i = 0; i < n; i++) {
+      bytes[i] = Math.floor(Math.random() * 256)
+    }
+  }
+  return Array.from(bytes, b => b.toString(16).padStart(2, '0')).join('')
+}
+
+/**
+ * Return true if err is a network error or a retryable response.
+ */
+
+function retryable(err: any): boolean {
+  if (!(err instanceof ClientError)) {
+    return true
+  }
+  return !!err.retryable || [502, 503, 504].indexOf(err.status) >= 0
+}
+
+/**
+ * Return the delay in milliseconds before a retry, using the retry-after hint of err when provided.
+ */
+
+function backoff(attempt: number, err: any): number {
+  if (err instanceof ClientError && err.retryAfter) {
+    return err.retryAfter * 1000
+  }
+  return 100 * Math.pow(2, attempt)
+}
+
+/**
+ * Sleep for ms milliseconds.
+ */
+
+function sleep(ms: number): Promise<void> {
+  return new Promise(resolve => setTimeout(resolve, ms))
+}
+
+/**
+ * Stream the outputs of method with params via a POST request, throwing when
+ * the stream is truncated before its end frame.
+ */
+
+async function* stream(url: string, method: string, authToken?: string, params?: any, options?: CallOptions): AsyncIterableIterator<any> {
+  const headers: Record<string, string> = {
+    'Content-Type': 'application/json',
+    'Accept': 'application/x-ndjson'
+  }
+
+  if (authToken != null) {
+    headers['Authorization'] = ` + "`Bearer ${authToken}`" + `
+  }
+
+  headers['traceparent'] = (options && options.traceparent) || newTraceparent()
+
+  const { signal, clear } = abortable(headers, options)
+  try {
+    const res = await fetch(url + '/' + method, {
+      method: 'POST',
+      body: JSON.stringify(params),
+      headers,
+      signal
+    })
+
+    if (res.status >= 300) {
+      throw await error(res)
+    }
+
+    const utf8 = new TextDecoder()
+    let buffer = ''
+
+    for await (const chunk of chunks(res.body)) {
+      buffer += utf8.decode(chunk, { stream: true })
+
+      let i
+      while ((i = buffer.indexOf('\n')) >= 0) {
+        const line = buffer.slice(0, i).trim()
+        buffer = buffer.slice(i + 1)
+        if (!line) {
+          continue
+        }
+
+        const f = frame(line)
+        if (f.end) {
+          return
+        }
+        yield f.data
+      }
+    }
+
+    throw new ClientError(0, 'Stream ended without an end frame')
+  } finally {
+    clear()
+  }
+}
+
+/**
+ * Iterate the chunks of a response body, canceling it when iteration stops early.
+ */
+
+async function* chunks(body: any): AsyncIterableIterator<Uint8Array> {
+  // node streams are async iterable
+  if (!body.getReader) {
+    yield* body
+    return
+  }
+
+  const reader = body.getReader()
+  try {
+    while (true) {
+      const { done, value } = await reader.read()
+      if (done) {
+        return
+      }
+      yield value
     }
-    throw err
+  } finally {
+    reader.cancel()
+  }
+}
+
+/**
+ * Decode a stream frame holding data or the end of the stream, throwing its error if any.
+ */
+
+function frame(line: string): { data?: any, end?: boolean } {
+  const f = JSON.parse(line, decoder)
+  if (f.error) {
+    throw fromBody(0, f.error)
   }
+  return f
+}
 
-  return res.text()
+/**
+ * BatchCall is a call in a batch, settled once the batch is sent.
+ */
+
+interface BatchCall {
+  method: string
+  input?: any
+  resolve(value: any): void
+  reject(err: any): void
+}
+
+/**
+ * Settle the batched calls with their results.
+ */
+
+function settle(calls: BatchCall[], results: any[]) {
+  calls.forEach((call, i) => {
+    const res = results[i]
+    if (res == null) {
+      call.reject(new ClientError(0, 'Missing batch result'))
+    } else if (res.error) {
+      call.reject(fromBody(res.status, res.error))
+    } else {
+      call.resolve(res.result)
+    }
+  })
+}
+
+/**
+ * Create an error from a well-formed error response,
+ * otherwise default to the status code.
+ */
+
+async function error(res: any, codec?: Codec): Promise<ClientError> {
+  let err: ClientError
+  try {
+    err = fromBody(res.status, await decode(res, codec))
+  } catch {
+    err = new ClientError(res.status, res.statusText)
+  }
+  err.requestId = err.requestId || res.headers.get('X-Request-Id') || undefined
+  return err
+}
+
+/**
+ * Create an error from the body of an error response.
+ */
+
+function fromBody(status: number, body: any): ClientError {
+  const { type, message, fields, details, retryable, retry_after, request_id } = body
+  const err = newError(status, message, type, fields, details)
+  err.retryable = retryable
+  err.retryAfter = retry_after
+  err.requestId = request_id
+  return err
+}
+
+/**
+ * Compress the request body with gzip. Response bodies are
+ * decompressed by fetch according to their Content-Encoding.
+ */
+
+async function compress(body: string | Uint8Array): Promise<Uint8Array> {
+  const stream = new Blob([body]).stream().pipeThrough(new CompressionStream('gzip'))
+  return new Uint8Array(await new Response(stream).arrayBuffer())
+}
+
+/**
+ * Decode the response body using the codec when it matches
+ * the response Content-Type, otherwise as JSON.
+ */
+
+async function decode(res: any, codec?: Codec): Promise<any> {
+  if (res.status == 204) {
+    return
+  }
+
+  if (codec && res.headers.get('Content-Type') == codec.contentType) {
+    return codec.decode(new Uint8Array(await res.arrayBuffer()))
+  }
+
+  return JSON.parse(await res.text(), decoder)
 }`
 
 // Generate writes the TS client implementations to w.
@@ -72,10 +496,21 @@ func Generate(w io.Writer, s *schema.Schema, fetchLibrary string) error {
 
 	out(w, require, fetchLibrary)
 	out(w, "\n%s\n", call)
-	out(w, "\n\n")
+	writeErrors(w, s)
+	out(w, "\n")
 	out(w, `const reISO8601 = /(\d{4}-[01]\d-[0-3]\dT[0-2]\d:[0-5]\d:[0-5]\d\.\d+([+-][0-2]\d:[0-5]\d|Z))|(\d{4}-[01]\d-[0-3]\dT[0-2]\d:[0-5]\d:[0-5]\d([+-][0-2]\d:[0-5]\d|Z))|(\d{4}-[01]\d-[0-3]\dT[0-2]\d:[0-5]\d([+-][0-2]\d:[0-5]\d|Z))/`)
 	out(w, "\n\n")
 	out(w, "/**\n")
+	out(w, " * Decoder is used as the reviver parameter when decoding JSON responses.\n")
+	out(w, " */\n")
+	out(w, "\n")
+	out(w, "function decoder(key: any, value: any) {\n")
+	out(w, "  return typeof value == 'string' && reISO8601.test(value)\n")
+	out(w, "    ? new Date(value)\n")
+	out(w, "    : value\n")
+	out(w, "}\n")
+	out(w, "\n")
+	out(w, "/**\n")
 	out(w, " * Client is the API client.\n")
 	out(w, " */\n")
 	out(w, "\n")
@@ -83,24 +518,27 @@ func Generate(w io.Writer, s *schema.Schema, fetchLibrary string) error {
 	out(w, "\n")
 	out(w, "  private url: string\n")
 	out(w, "  private authToken?: string\n")
+	out(w, "  private codec?: Codec\n")
+	out(w, "  private retries: number\n")
 	out(w, "\n")
 	out(w, "  /**\n")
-	out(w, "   * Initialize.\n")
+	out(w, "   * Initialize, retries is the number of times calls of idempotent and safe methods are\n")
+	out(w, "   * retried after network errors or retryable responses, defaulting to none.\n")
 	out(w, "   */\n")
 	out(w, "\n")
-	out(w, "  constructor(params: { url: string, authToken?: string }) {\n")
+	out(w, "  constructor(params: { url: string, authToken?: string, codec?: Codec, retries?: number }) {\n")
 	out(w, "    this.url = params.url\n")
 	out(w, "    this.authToken = params.authToken\n")
+	out(w, "    this.codec = params.codec\n")
+	out(w, "    this.retries = params.retries || 0\n")
 	out(w, "  }\n")
 	out(w, "\n")
 	out(w, "  /**\n")
-	out(w, "   * Decoder is used as the reviver parameter when decoding responses.\n")
+	out(w, "   * newBatch returns a new batch of calls sent in a single request.\n")
 	out(w, "   */\n")
 	out(w, "\n")
-	out(w, "  private decoder(key: any, value: any) {\n")
-	out(w, "    return typeof value == 'string' && reISO8601.test(value)\n")
-	out(w, "      ? new Date(value)\n")
-	out(w, "      : value\n")
+	out(w, "  newBatch(): Batch {\n")
+	out(w, "    return new Batch({ url: this.url, authToken: this.authToken, codec: this.codec })\n")
 	out(w, "  }\n")
 	out(w, "\n")
 
@@ -109,13 +547,30 @@ func Generate(w io.Writer, s *schema.Schema, fetchLibrary string) error {
 		name := format.JsName(m.Name)
 		out(w, "  /**\n")
 		out(w, "   * %s: %s\n", name, m.Description)
+		if len(m.Scopes) > 0 {
+			out(w, "   *\n")
+			out(w, "   * %s\n", format.Scopes(m.Scopes))
+		}
 		out(w, "   */\n\n")
 
+		// stream
+		if m.Stream {
+			if len(m.Inputs) > 0 {
+				out(w, "  async *%s(params: %sInput, options?: CallOptions): AsyncIterableIterator<%sOutput> {\n", name, format.GoName(m.Name), format.GoName(m.Name))
+				out(w, "    yield* stream(this.url, '%s', this.authToken, params, options)\n", m.Name)
+			} else {
+				out(w, "  async *%s(options?: CallOptions): AsyncIterableIterator<%sOutput> {\n", name, format.GoName(m.Name))
+				out(w, "    yield* stream(this.url, '%s', this.authToken, undefined, options)\n", m.Name)
+			}
+			out(w, "  }\n\n")
+			continue
+		}
+
 		// input
 		if len(m.Inputs) > 0 {
-			out(w, "  async %s(params: %sInput)", name, format.GoName(m.Name))
+			out(w, "  async %s(params: %sInput, options?: CallOptions)", name, format.GoName(m.Name))
 		} else {
-			out(w, "  async %s()", name)
+			out(w, "  async %s(options?: CallOptions)", name)
 		}
 
 		// output
@@ -127,22 +582,26 @@ func Generate(w io.Writer, s *schema.Schema, fetchLibrary string) error {
 
 		// return
 		if len(m.Outputs) > 0 {
-			out(w, "    let res = ")
-			// call
-			if len(m.Inputs) > 0 {
-				out(w, "await call(this.url, '%s', this.authToken, params)\n", m.Name)
-			} else {
-				out(w, "await call(this.url, '%s', this.authToken)\n", m.Name)
-			}
-			out(w, "    let out: %sOutput = JSON.parse(res, this.decoder)\n", format.GoName(m.Name))
-			out(w, "    return out\n")
+			out(w, "    let out: %sOutput = ", format.GoName(m.Name))
 		} else {
-			// call
-			if len(m.Inputs) > 0 {
-				out(w, "    await call(this.url, '%s', this.authToken, params)\n", m.Name)
-			} else {
-				out(w, "    await call(this.url, '%s', this.authToken)\n", m.Name)
-			}
+			out(w, "    ")
+		}
+
+		// call
+		params := "undefined"
+		if len(m.Inputs) > 0 {
+			params = "params"
+		}
+		if m.Safe {
+			out(w, "await callSafe(this.url, '%s', this.authToken, %s, this.codec, this.retries, options)\n", m.Name, params)
+		} else if m.Idempotent {
+			out(w, "await callIdempotent(this.url, '%s', this.authToken, %s, this.codec, this.retries, options)\n", m.Name, params)
+		} else {
+			out(w, "await call(this.url, '%s', this.authToken, %s, this.codec, undefined, options)\n", m.Name, params)
+		}
+
+		if len(m.Outputs) > 0 {
+			out(w, "    return out\n")
 		}
 
 		out(w, "  }\n\n")
@@ -150,5 +609,151 @@ func Generate(w io.Writer, s *schema.Schema, fetchLibrary string) error {
 
 	out(w, "}\n")
 
+	writeBatch(w, s)
+
 	return nil
 }
+
+// writeBatch writes the batch class to w.
+func writeBatch(w io.Writer, s *schema.Schema) {
+	out := fmt.Fprintf
+
+	out(w, "\n")
+	out(w, "/**\n")
+	out(w, " * Batch is a batch of calls sent in a single request, the promise\n")
+	out(w, " * returned by each call is settled once the batch is sent.\n")
+	out(w, " */\n")
+	out(w, "\n")
+	out(w, "export class Batch {\n")
+	out(w, "\n")
+	out(w, "  private url: string\n")
+	out(w, "  private authToken?: string\n")
+	out(w, "  private codec?: Codec\n")
+	out(w, "  private calls: BatchCall[] = []\n")
+	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * Initialize.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  constructor(params: { url: string, authToken?: string, codec?: Codec }) {\n")
+	out(w, "    this.url = params.url\n")
+	out(w, "    this.authToken = params.authToken\n")
+	out(w, "    this.codec = params.codec\n")
+	out(w, "  }\n")
+	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * send the batch, rejecting the calls and throwing when the request fails.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  async send(options?: CallOptions) {\n")
+	out(w, "    const calls = this.calls\n")
+	out(w, "    this.calls = []\n")
+	out(w, "\n")
+	out(w, "    try {\n")
+	out(w, "      const params = calls.map(({ method, input }) => ({ method, input }))\n")
+	out(w, "      settle(calls, await call(this.url, '_batch', this.authToken, params, this.codec, undefined, options))\n")
+	out(w, "    } catch (err) {\n")
+	out(w, "      calls.forEach(call => call.reject(err))\n")
+	out(w, "      throw err\n")
+	out(w, "    }\n")
+	out(w, "  }\n")
+	out(w, "\n")
+	out(w, "  /**\n")
+	out(w, "   * add a call of method to the batch.\n")
+	out(w, "   */\n")
+	out(w, "\n")
+	out(w, "  private add(method: string, input?: any): Promise<any> {\n")
+	out(w, "    const promise = new Promise((resolve, reject) => {\n")
+	out(w, "      this.calls.push({ method, input, resolve, reject })\n")
+	out(w, "    })\n")
+	out(w, "    // rejections are also reported by send()\n")
+	out(w, "    promise.catch(() => {})\n")
+	out(w, "    return promise\n")
+	out(w, "  }\n")
+
+	for _, m := range s.Methods {
+		if m.Stream {
+			continue
+		}
+
+		name := format.JsName(m.Name)
+		out(w, "\n")
+		out(w, "  /**\n")
+		out(w, "   * %s: %s\n", name, m.Description)
+		if len(m.Scopes) > 0 {
+			out(w, "   *\n")
+			out(w, "   * %s\n", format.Scopes(m.Scopes))
+		}
+		out(w, "   */\n\n")
+
+		// input
+		if len(m.Inputs) > 0 {
+			out(w, "  %s(params: %sInput)", name, format.GoName(m.Name))
+		} else {
+			out(w, "  %s()", name)
+		}
+
+		// output
+		if len(m.Outputs) > 0 {
+			out(w, ": Promise<%sOutput> {\n", format.GoName(m.Name))
+		} else {
+			out(w, ": Promise<void> {\n")
+		}
+
+		// call
+		if len(m.Inputs) > 0 {
+			out(w, "    return this.add('%s', params)\n", m.Name)
+		} else {
+			out(w, "    return this.add('%s')\n", m.Name)
+		}
+		out(w, "  }\n")
+	}
+
+	out(w, "}\n")
+}
+
+// writeErrors writes the typed error classes declared in the schema to w.
+func writeErrors(w io.Writer, s *schema.Schema) {
+	out := fmt.Fprintf
+
+	for _, e := range s.ErrorsSlice() {
+		name := format.GoName(e.Name) + "Error"
+		out(w, "\n/**\n")
+		out(w, " * %s %s\n", name, e.Description)
+		out(w, " */\n")
+		out(w, "\n")
+		out(w, "export class %s extends ClientError {\n", name)
+		if len(e.Details) > 0 {
+			out(w, "  details?: %sDetails;\n", name)
+			out(w, "\n")
+			out(w, "  constructor(status: number, message?: string, details?: %sDetails) {\n", name)
+			out(w, "    super(status, message, '%s')\n", e.Name)
+			out(w, "    this.details = details\n")
+		} else {
+			out(w, "  constructor(status: number, message?: string) {\n")
+			out(w, "    super(status, message, '%s')\n", e.Name)
+		}
+		out(w, "  }\n")
+		out(w, "}\n")
+	}
+
+	out(w, "\n/**\n")
+	out(w, " * Create an error, typed when declared in the schema.\n")
+	out(w, " */\n")
+	out(w, "\n")
+	out(w, "function newError(status: number, message?: string, type?: string, fields?: FieldError[], details?: any): ClientError {\n")
+	out(w, "  switch (type) {\n")
+	for _, e := range s.ErrorsSlice() {
+		name := format.GoName(e.Name) + "Error"
+		out(w, "    case '%s':\n", e.Name)
+		if len(e.Details) > 0 {
+			out(w, "      return new %s(status, message, details)\n", name)
+		} else {
+			out(w, "      return new %s(status, message)\n", name)
+		}
+	}
+	out(w, "    default:\n")
+	out(w, "      return new ClientError(status, message, type, fields)\n")
+	out(w, "  }\n")
+	out(w, "}\n")
+}
@@ -10,6 +10,12 @@ export interface Item {
   text: string
 }
 
+// ItemNotFoundErrorDetails is the details of ItemNotFoundError.
+export interface ItemNotFoundErrorDetails {
+  // id is the id of the item.
+  id?: number
+}
+
 // AddItemInput params.
 interface AddItemInput {
   // item is the item to add. This field is required.
@@ -34,3 +40,9 @@ interface RemoveItemOutput {
   item?: Item
 }
 
+// WatchItemsOutput params.
+interface WatchItemsOutput {
+  // item is the item added.
+  item?: Item
+}
+
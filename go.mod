@@ -3,11 +3,15 @@ module github.com/apex/rpc
 go 1.13
 
 require (
+	github.com/fxamacker/cbor/v2 v2.5.0
 	github.com/gookit/color v1.2.6 // indirect
 	github.com/iancoleman/strcase v0.0.0-20191112232945-16388991a334
 	github.com/json-iterator/go v1.1.9
+	github.com/klauspost/compress v1.11.13
+	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/sergi/go-diff v1.1.0 // indirect
 	github.com/tj/assert v0.0.0-20190920132354-ee03d75cd160
 	github.com/tj/go-fixture v1.0.0
+	github.com/vmihailenco/msgpack/v5 v5.3.5
 	github.com/xeipuuv/gojsonschema v1.2.0
 )
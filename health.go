@@ -1,14 +1,16 @@
 package rpc
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sort"
+	"sync"
+	"time"
 )
 
-// TODO: remove health checking, this can be provided
-// in middleware and has nothing to do with RPC :D
-
-// HealthChecker is the interface used for servers providing a health check.
+// HealthChecker is the interface used for servers providing a health check,
+// which is reported as the "health" readiness check.
 type HealthChecker interface {
 	Health() error
 }
@@ -27,3 +29,209 @@ func WriteHealth(w http.ResponseWriter, s interface{}) {
 
 	fmt.Fprintln(w, "OK")
 }
+
+// HealthProbe is the kind of health probe.
+type HealthProbe int
+
+// Health probes.
+const (
+	// Liveness probes report whether the server is running, failing when it
+	// should be restarted.
+	Liveness HealthProbe = iota
+
+	// Readiness probes report whether the server is able to serve calls,
+	// failing when it should not receive traffic.
+	Readiness
+)
+
+// DefaultHealthTimeout is the timeout of health checks which do not specify one.
+var DefaultHealthTimeout = 5 * time.Second
+
+// HealthCheckFunc is a health check, returning an error when it fails.
+type HealthCheckFunc func(ctx context.Context) error
+
+// HealthChecksProvider is the interface used for servers providing health checks.
+type HealthChecksProvider interface {
+	HealthChecks() *HealthChecks
+}
+
+// HealthChecks is a set of named health checks.
+type HealthChecks struct {
+	mu     sync.Mutex
+	checks []healthCheck
+}
+
+// healthCheck is a registered health check.
+type healthCheck struct {
+	name    string
+	timeout time.Duration
+	live    bool
+	check   HealthCheckFunc
+}
+
+// NewHealthChecks returns a new set of health checks.
+func NewHealthChecks() *HealthChecks {
+	return &HealthChecks{}
+}
+
+// Live registers a liveness check, which is also run by readiness probes.
+// A zero timeout defaults to DefaultHealthTimeout.
+func (h *HealthChecks) Live(name string, timeout time.Duration, check HealthCheckFunc) {
+	h.add(healthCheck{name: name, timeout: timeout, live: true, check: check})
+}
+
+// Ready registers a readiness check. A zero timeout defaults to DefaultHealthTimeout.
+func (h *HealthChecks) Ready(name string, timeout time.Duration, check HealthCheckFunc) {
+	h.add(healthCheck{name: name, timeout: timeout, check: check})
+}
+
+// add registers check.
+func (h *HealthChecks) add(check healthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, check)
+}
+
+// HealthReport is the report of a health probe.
+type HealthReport struct {
+	Name    string              `json:"name"`
+	Version string              `json:"version"`
+	Status  string              `json:"status"`
+	Checks  []HealthCheckReport `json:"checks"`
+}
+
+// HealthCheckReport is the report of a health check.
+type HealthCheckReport struct {
+	Name    string  `json:"name"`
+	Status  string  `json:"status"`
+	Latency float64 `json:"latency_ms"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// Health check statuses.
+const (
+	HealthOK     = "ok"
+	HealthFailed = "failed"
+)
+
+// CheckHealth runs the checks of probe concurrently, returning the report of
+// the server s, which fails when any of its checks fail or time out.
+//
+// Checks are provided by s when it implements HealthChecksProvider, and the
+// Health() method of servers implementing HealthChecker is a readiness check.
+func CheckHealth(ctx context.Context, s interface{}, probe HealthProbe) HealthReport {
+	var checks []healthCheck
+
+	if p, ok := s.(HealthChecksProvider); ok {
+		h := p.HealthChecks()
+		h.mu.Lock()
+		for _, c := range h.checks {
+			if c.live || probe == Readiness {
+				checks = append(checks, c)
+			}
+		}
+		h.mu.Unlock()
+	}
+
+	if h, ok := s.(HealthChecker); ok && probe == Readiness {
+		checks = append(checks, healthCheck{
+			name: "health",
+			check: func(context.Context) error {
+				return h.Health()
+			},
+		})
+	}
+
+	report := HealthReport{
+		Status: HealthOK,
+		Checks: make([]HealthCheckReport, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c healthCheck) {
+			defer wg.Done()
+			report.Checks[i] = runHealthCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	sort.Slice(report.Checks, func(i, j int) bool {
+		return report.Checks[i].Name < report.Checks[j].Name
+	})
+
+	for _, c := range report.Checks {
+		if c.Status != HealthOK {
+			report.Status = HealthFailed
+		}
+	}
+
+	return report
+}
+
+// runHealthCheck runs c, reporting it as failed when it does not return
+// within its timeout.
+func runHealthCheck(ctx context.Context, c healthCheck) HealthCheckReport {
+	timeout := c.timeout
+	if timeout <= 0 {
+		timeout = DefaultHealthTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				done <- fmt.Errorf("panic: %v", v)
+			}
+		}()
+		done <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("Timed out after %s", timeout)
+	}
+
+	report := HealthCheckReport{
+		Name:    c.name,
+		Status:  HealthOK,
+		Latency: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+
+	if err != nil {
+		report.Status = HealthFailed
+		report.Error = err.Error()
+	}
+
+	return report
+}
+
+// WriteHealthReport responds with the JSON report of probe for the server s
+// of the schema with the given name and version, with the status 200 when it
+// passes, otherwise 503.
+func WriteHealthReport(w http.ResponseWriter, r *http.Request, s interface{}, probe HealthProbe, name, version string) {
+	report := CheckHealth(r.Context(), s, probe)
+	report.Name = name
+	report.Version = version
+
+	status := http.StatusOK
+	if report.Status != HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -1,12 +1,15 @@
 package rpc_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/tj/assert"
-	
+
 	"github.com/apex/rpc"
 )
 
@@ -43,3 +46,90 @@ func TestWriteHealth(t *testing.T) {
 		assert.Equal(t, "Health check failed\n", w.Body.String())
 	})
 }
+
+// healthServer is a server providing health checks.
+type healthServer struct {
+	healthChecker
+	checks *rpc.HealthChecks
+}
+
+// HealthChecks implementation.
+func (s healthServer) HealthChecks() *rpc.HealthChecks {
+	return s.checks
+}
+
+// Test health reports.
+func TestWriteHealthReport(t *testing.T) {
+	ok := func(ctx context.Context) error {
+		return nil
+	}
+
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	failing := func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}
+
+	t.Run("without checks", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/_health/live", nil)
+		rpc.WriteHealthReport(w, r, struct{}{}, rpc.Liveness, "todo", "1.0.0")
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"name":"todo","version":"1.0.0","status":"ok","checks":[]}`, w.Body.String())
+	})
+
+	t.Run("with passing checks", func(t *testing.T) {
+		checks := rpc.NewHealthChecks()
+		checks.Live("loop", 0, ok)
+		checks.Ready("database", time.Second, ok)
+		s := healthServer{checks: checks}
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/_health/ready", nil)
+		rpc.WriteHealthReport(w, r, s, rpc.Readiness, "todo", "1.0.0")
+		assert.Equal(t, 200, w.Code)
+
+		var report rpc.HealthReport
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.Equal(t, rpc.HealthOK, report.Status)
+		assert.Len(t, report.Checks, 3)
+		assert.Equal(t, "database", report.Checks[0].Name)
+		assert.Equal(t, "health", report.Checks[1].Name)
+		assert.Equal(t, "loop", report.Checks[2].Name)
+	})
+
+	t.Run("with a liveness probe", func(t *testing.T) {
+		checks := rpc.NewHealthChecks()
+		checks.Live("loop", 0, ok)
+		checks.Ready("database", 0, failing)
+		s := healthServer{healthChecker{errors.New("boom")}, checks}
+
+		report := rpc.CheckHealth(context.Background(), s, rpc.Liveness)
+		assert.Equal(t, rpc.HealthOK, report.Status)
+		assert.Len(t, report.Checks, 1)
+		assert.Equal(t, "loop", report.Checks[0].Name)
+	})
+
+	t.Run("with failing checks", func(t *testing.T) {
+		checks := rpc.NewHealthChecks()
+		checks.Ready("cache", 10*time.Millisecond, slow)
+		checks.Ready("database", 0, failing)
+		s := healthServer{checks: checks}
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/_health/ready", nil)
+		rpc.WriteHealthReport(w, r, s, rpc.Readiness, "todo", "1.0.0")
+		assert.Equal(t, 503, w.Code)
+
+		var report rpc.HealthReport
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.Equal(t, rpc.HealthFailed, report.Status)
+		assert.Equal(t, rpc.HealthCheckReport{Name: "cache", Status: rpc.HealthFailed, Latency: report.Checks[0].Latency, Error: "Timed out after 10ms"}, report.Checks[0])
+		assert.Equal(t, "connection refused", report.Checks[1].Error)
+		assert.True(t, report.Checks[0].Latency >= 10)
+	})
+}
@@ -0,0 +1,444 @@
+package rpc
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotentResponse is an encoded response stored for an idempotency key.
+type IdempotentResponse struct {
+	// RequestHash is the hash of the request which used the key.
+	RequestHash string
+
+	// Status is the HTTP status code.
+	Status int
+
+	// Header holds the response headers of errors, such as Retry-After.
+	Header http.Header
+
+	// ContentType is the media type of Body.
+	ContentType string
+
+	// Body is the uncompressed response body.
+	Body []byte
+}
+
+// IdempotencyStore is the interface used for storing the responses of
+// idempotent methods by key.
+type IdempotencyStore interface {
+	// Reserve reserves key for a new call, returning false when it was
+	// already reserved along with the stored response, or nil when the
+	// call is still in progress.
+	Reserve(ctx context.Context, key string) (*IdempotentResponse, bool, error)
+
+	// Save stores the response of the call which reserved key.
+	Save(ctx context.Context, key string, res IdempotentResponse) error
+
+	// Release removes the reservation of key so the call may be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyStoreProvider is the interface used for servers providing an idempotency store.
+type IdempotencyStoreProvider interface {
+	IdempotencyStore() IdempotencyStore
+}
+
+// DefaultIdempotencyStore is the store used by servers which do not provide one.
+var DefaultIdempotencyStore IdempotencyStore = NewMemoryIdempotencyStore(24 * time.Hour)
+
+// ServeIdempotent writes the result of call, storing the response under the
+// Idempotency-Key header of r, scoped to the request path and principal, or
+// the client IP address of unauthenticated requests. Servers behind a proxy
+// should authenticate idempotent methods, as the proxy address is shared by
+// its clients. Requests with a key already used are answered with the stored
+// response, or a retryable 409 conflict while the first call is in progress.
+// Requests reusing a key with a different method, path, query-string, content
+// type or body are rejected with a 422 error, bodies being hashed up to the
+// MaxBytes limit of the options. Internal and retryable errors, including
+// responses which fail to encode, and calls which panic are not stored so the
+// call may be retried, and failures to store responses are logged.
+//
+// The store is provided by s when it implements IdempotencyStoreProvider,
+// otherwise DefaultIdempotencyStore is used.
+func ServeIdempotent(w http.ResponseWriter, r *http.Request, s interface{}, call func() (interface{}, error), options ...ReadOption) {
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		res, err := call()
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		WriteResponse(w, res)
+		return
+	}
+
+	store := DefaultIdempotencyStore
+	if p, ok := s.(IdempotencyStoreProvider); ok {
+		store = p.IdempotencyStore()
+	}
+
+	ctx := r.Context()
+	limits := newReadOptions(options).limits.merge(DefaultLimits)
+	key = r.URL.Path + " " + key
+
+	// keys are scoped to the principal, or the client of unauthenticated requests
+	if p := PrincipalFromContext(ctx); p != nil {
+		key = "principal " + p.Subject + " " + key
+	} else {
+		key = "ip " + remoteHost(r) + " " + key
+	}
+
+	// replay
+	stored, ok, err := store.Reserve(ctx, key)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if !ok && stored == nil {
+		WriteError(w, Error(http.StatusConflict, "conflict", "A request with this Idempotency-Key is in progress", WithRetryAfter(time.Second)))
+		return
+	}
+
+	if !ok {
+		// stores may not persist the hash of responses saved by earlier versions
+		if stored.RequestHash != "" && stored.RequestHash != hashRequest(r, nil, limits) {
+			WriteError(w, Error(http.StatusUnprocessableEntity, "idempotency_key_reused", "The Idempotency-Key was used by a different request"))
+			return
+		}
+		replay(w, *stored)
+		return
+	}
+
+	// the reservation is released when call panics
+	settled := false
+	defer func() {
+		if !settled {
+			store.Release(ctx, key)
+		}
+	}()
+
+	// call, hashing the body as it is read
+	h := requestHasher(r)
+	r.Body = hashedBody{io.TeeReader(r.Body, h), r.Body}
+	res, err := call()
+	stored = record(w, res, err)
+	stored.RequestHash = hashRequest(r, h, limits)
+
+	if stored.Status >= 500 || (err != nil && retryable(err)) {
+		err = store.Release(ctx, key)
+	} else {
+		err = store.Save(ctx, key, *stored)
+	}
+	settled = true
+
+	// the call has completed, so its response is written regardless
+	if err != nil {
+		log.Printf("rpc: storing response for idempotency key %q: %s", key, err)
+	}
+
+	if stored.Status == http.StatusNoContent {
+		w.WriteHeader(stored.Status)
+		return
+	}
+
+	writeBody(w, stored.Status, stored.ContentType, stored.Body)
+}
+
+// hashedBody is a request body hashed as it is read.
+type hashedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// requestHasher returns a hash of the method, path, query-string and body
+// encoding of r, to which its body is written.
+func requestHasher(r *http.Request) hash.Hash {
+	h := sha256.New()
+	for _, s := range []string{r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Get("Content-Type"), r.Header.Get("Content-Encoding")} {
+		io.WriteString(h, s)
+		h.Write([]byte{0})
+	}
+	return h
+}
+
+// hashRequest returns the hash of r, reading the remainder of its body up
+// to l.MaxBytes hashed into h, or hashing all of it when h is nil.
+func hashRequest(r *http.Request, h hash.Hash, l Limits) string {
+	var dst io.Writer = ioutil.Discard
+	if h == nil {
+		h = requestHasher(r)
+		dst = h
+	}
+	if r.Body != nil {
+		var body io.Reader = r.Body
+		if l.MaxBytes > 0 {
+			body = io.LimitReader(body, l.MaxBytes)
+		}
+		io.Copy(dst, body)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// record returns the response for the result of a call encoded with the
+// codec negotiated for w, setting the response headers of errors.
+func record(w http.ResponseWriter, res interface{}, err error) *IdempotentResponse {
+	status := http.StatusOK
+	var value interface{} = res
+	var header http.Header
+
+	if err != nil {
+		var body serverErrorResponse
+		status, body = errorResponse(err, w.Header().Get("X-Request-Id"))
+		header = errorHeader(err, body)
+		setErrorHeader(w, err, body)
+		value = body
+	}
+
+	if value == nil {
+		return &IdempotentResponse{Status: http.StatusNoContent}
+	}
+
+	var buf bytes.Buffer
+	c, err := encode(w, &buf, value)
+	if err != nil {
+		status, c = encodeError(w, &buf, err)
+		header = nil
+	}
+
+	return &IdempotentResponse{
+		Status:      status,
+		Header:      header,
+		ContentType: c.ContentType(),
+		Body:        buf.Bytes(),
+	}
+}
+
+// replay writes the stored response res with its headers, re-encoding it
+// when the codec negotiated for w differs.
+func replay(w http.ResponseWriter, res IdempotentResponse) {
+	for k, v := range res.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+
+	if res.Status == http.StatusNoContent {
+		w.WriteHeader(res.Status)
+		return
+	}
+
+	c, ok := LookupCodec(res.ContentType)
+	if !ok || c.ContentType() == negotiatedCodec(w).ContentType() {
+		writeBody(w, res.Status, res.ContentType, res.Body)
+		return
+	}
+
+	v, err := decodeStored(c, res.Body)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	write(w, res.Status, v)
+}
+
+// decodeStored decodes the stored body b with c, preserving the integers of JSON bodies.
+func decodeStored(c Codec, b []byte) (interface{}, error) {
+	var v interface{}
+
+	if c != JSON {
+		err := c.Decode(bytes.NewReader(b), &v)
+		return v, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	err := dec.Decode(&v)
+	return numbers(v), err
+}
+
+// number is a JSON number decoded with UseNumber.
+type number interface {
+	Int64() (int64, error)
+	Float64() (float64, error)
+}
+
+// numbers returns v with JSON numbers converted to integers or floats.
+func numbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = numbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = numbers(e)
+		}
+	case number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+// retryable returns true if the error chain of err provides a retryable hint.
+func retryable(err error) bool {
+	e := RetryProvider(nil)
+	return errors.As(err, &e) && e.Retryable()
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore, expiring keys after
+// a TTL and evicting the oldest stored responses beyond its maximum number of
+// entries or bytes. Keys of calls in progress are never evicted, and new keys
+// are rejected with a retryable 503 error while they alone fill the store.
+type MemoryIdempotencyStore struct {
+	ttl        time.Duration
+	maxEntries int
+	maxBytes   int64
+
+	mu      sync.Mutex
+	bytes   int64
+	pending int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// memoryEntry is a reserved key, with a nil response while the call is in progress.
+type memoryEntry struct {
+	key     string
+	res     *IdempotentResponse
+	size    int64
+	expires time.Time
+}
+
+// Default limits of in-memory idempotency stores.
+const (
+	DefaultIdempotencyMaxEntries = 10000
+	DefaultIdempotencyMaxBytes   = 64 << 20
+)
+
+// NewMemoryIdempotencyStore returns a new in-memory store expiring keys after ttl,
+// limited to DefaultIdempotencyMaxEntries keys and DefaultIdempotencyMaxBytes.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return NewMemoryIdempotencyStoreSize(ttl, DefaultIdempotencyMaxEntries, DefaultIdempotencyMaxBytes)
+}
+
+// NewMemoryIdempotencyStoreSize returns a new in-memory store expiring keys after
+// ttl, evicting the oldest keys beyond maxEntries keys or maxBytes of responses.
+func NewMemoryIdempotencyStoreSize(ttl time.Duration, maxEntries int, maxBytes int64) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Reserve implementation.
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, key string) (*IdempotentResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	if el, ok := s.entries[key]; ok {
+		return el.Value.(*memoryEntry).res, false, nil
+	}
+
+	if s.maxEntries > 0 && s.pending >= s.maxEntries {
+		return nil, false, Error(http.StatusServiceUnavailable, "unavailable", "Too many requests with an Idempotency-Key are in progress", WithRetryAfter(time.Second))
+	}
+
+	s.put(&memoryEntry{key: key, size: int64(len(key)), expires: now.Add(s.ttl)})
+	return nil, true, nil
+}
+
+// Save implementation.
+func (s *MemoryIdempotencyStore) Save(ctx context.Context, key string, res IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	size := int64(len(key) + len(res.RequestHash) + len(res.ContentType) + len(res.Body))
+	for k, v := range res.Header {
+		size += int64(len(k))
+		for _, s := range v {
+			size += int64(len(s))
+		}
+	}
+
+	s.put(&memoryEntry{key: key, res: &res, size: size, expires: time.Now().Add(s.ttl)})
+	return nil
+}
+
+// Release implementation.
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.remove(el)
+	}
+
+	return nil
+}
+
+// put stores e as the newest entry, evicting the oldest stored responses
+// beyond the limits. Reservations of calls in progress are kept, as their
+// keys could otherwise be reserved again by a retry.
+func (s *MemoryIdempotencyStore) put(e *memoryEntry) {
+	if el, ok := s.entries[e.key]; ok {
+		s.remove(el)
+	}
+
+	s.entries[e.key] = s.order.PushBack(e)
+	s.bytes += e.size
+	if e.res == nil {
+		s.pending++
+	}
+
+	for el := s.order.Front(); el != nil && s.full(); {
+		next := el.Next()
+		if v := el.Value.(*memoryEntry); v != e && v.res != nil {
+			s.remove(el)
+		}
+		el = next
+	}
+}
+
+// full returns true if the entries exceed the limits.
+func (s *MemoryIdempotencyStore) full() bool {
+	return (s.maxEntries > 0 && s.order.Len() > s.maxEntries) || (s.maxBytes > 0 && s.bytes > s.maxBytes)
+}
+
+// remove removes the entry of el.
+func (s *MemoryIdempotencyStore) remove(el *list.Element) {
+	e := s.order.Remove(el).(*memoryEntry)
+	delete(s.entries, e.key)
+	s.bytes -= e.size
+	if e.res == nil {
+		s.pending--
+	}
+}
+
+// sweep removes expired keys, which are the oldest as all keys share the TTL.
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	for el := s.order.Front(); el != nil && !now.Before(el.Value.(*memoryEntry).expires); el = s.order.Front() {
+		s.remove(el)
+	}
+}
@@ -0,0 +1,378 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// storeServer is a server providing an idempotency store.
+type storeServer struct {
+	store rpc.IdempotencyStore
+}
+
+// IdempotencyStore implementation.
+func (s storeServer) IdempotencyStore() rpc.IdempotencyStore {
+	return s.store
+}
+
+// serveIdempotent serves a request with key to call.
+func serveIdempotent(s interface{}, key, accept string, call func() (interface{}, error)) *httptest.ResponseRecorder {
+	return serveIdempotentBody(s, key, accept, `{}`, call)
+}
+
+// serveIdempotentBody serves a request with key and body to call, which reads the body.
+func serveIdempotentBody(s interface{}, key, accept, body string, call func() (interface{}, error)) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/add_item", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Idempotency-Key", key)
+	r.Header.Set("Accept", accept)
+	w := httptest.NewRecorder()
+	rpc.ServeIdempotent(rpc.Negotiate(w, r), r, s, func() (interface{}, error) {
+		var in map[string]interface{}
+		if err := rpc.ReadRequest(r, &in); err != nil {
+			return nil, err
+		}
+		return call()
+	})
+	return w
+}
+
+// Test idempotent calls.
+func TestServeIdempotent(t *testing.T) {
+	t.Run("with a replayed response", func(t *testing.T) {
+		s := storeServer{rpc.NewMemoryIdempotencyStore(time.Minute)}
+		calls := 0
+		call := func() (interface{}, error) {
+			calls++
+			return map[string]int{"calls": calls}, nil
+		}
+
+		w := serveIdempotent(s, "a", "", call)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "", w.Header().Get("Idempotent-Replayed"))
+		assert.JSONEq(t, `{"calls":1}`, w.Body.String())
+
+		w = serveIdempotent(s, "a", "", call)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+		assert.JSONEq(t, `{"calls":1}`, w.Body.String())
+
+		w = serveIdempotent(s, "b", "", call)
+		assert.JSONEq(t, `{"calls":2}`, w.Body.String())
+	})
+
+	t.Run("with a replayed response in another codec", func(t *testing.T) {
+		s := storeServer{rpc.NewMemoryIdempotencyStore(time.Minute)}
+		call := func() (interface{}, error) {
+			return map[string]int{"n": 1}, nil
+		}
+
+		serveIdempotent(s, "a", "", call)
+		w := serveIdempotent(s, "a", "application/msgpack", call)
+		assert.Equal(t, "application/msgpack", w.Header().Get("Content-Type"))
+
+		var v map[string]int
+		assert.NoError(t, rpc.MessagePack.Decode(w.Body, &v))
+		assert.Equal(t, map[string]int{"n": 1}, v)
+	})
+
+	t.Run("with a replayed error", func(t *testing.T) {
+		s := storeServer{rpc.NewMemoryIdempotencyStore(time.Minute)}
+		calls := 0
+		call := func() (interface{}, error) {
+			calls++
+			return nil, rpc.BadRequest("Nope")
+		}
+
+		serveIdempotent(s, "a", "", call)
+		w := serveIdempotent(s, "a", "", call)
+		assert.Equal(t, 400, w.Code)
+		assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("with a replayed error and its headers", func(t *testing.T) {
+		s := storeServer{rpc.NewMemoryIdempotencyStore(time.Minute)}
+		call := func() (interface{}, error) {
+			return nil, rpc.MethodNotAllowed("Nope", "POST")
+		}
+
+		w := serveIdempotent(s, "a", "", call)
+		assert.Equal(t, 405, w.Code)
+		assert.Equal(t, "POST", w.Header().Get("Allow"))
+
+		w = serveIdempotent(s, "a", "", call)
+		assert.Equal(t, 405, w.Code)
+		assert.Equal(t, "POST", w.Header().Get("Allow"))
+		assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("with a key reused by a different request", func(t *testing.T) {
+		s := storeServer{rpc.NewMemoryIdempotencyStore(time.Minute)}
+		calls := 0
+		call := func() (interface{}, error) {
+			calls++
+			return map[string]int{"calls": calls}, nil
+		}
+
+		w := serveIdempotentBody(s, "a", "", `{"item":"a"}`, call)
+		assert.Equal(t, 200, w.Code)
+
+		w = serveIdempotentBody(s, "a", "", `{"item":"b"}`, call)
+		assert.Equal(t, 422, w.Code)
+		assert.Contains(t, w.Body.String(), `"type":"idempotency_key_reused"`)
+
+		w = serveIdempotentBody(s, "a", "", `{"item":"a"}`, call)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("with a call which does not read the whole body", func(t *testing.T) {
+		s := storeServer{rpc.NewMemoryIdempotencyStore(time.Minute)}
+		serve := func(body string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest("POST", "/add_item", strings.NewReader(body))
+			r.Header.Set("Idempotency-Key", "a")
+			w := httptest.NewRecorder()
+			rpc.ServeIdempotent(w, r, s, func() (interface{}, error) {
+				return nil, nil
+			})
+			return w
+		}
+
+		assert.Equal(t, 204, serve(`{"item":"a"}`).Code)
+		assert.Equal(t, 204, serve(`{"item":"a"}`).Code)
+		assert.Equal(t, 422, serve(`{"item":"b"}`).Code)
+	})
+
+	t.Run("with an internal error", func(t *testing.T) {
+		s := storeServer{rpc.NewMemoryIdempotencyStore(time.Minute)}
+		calls := 0
+		call := func() (interface{}, error) {
+			calls++
+			return nil, errors.New("boom")
+		}
+
+		w := serveIdempotent(s, "a", "", call)
+		assert.Equal(t, 500, w.Code)
+		w = serveIdempotent(s, "a", "", call)
+		assert.Equal(t, 500, w.Code)
+		assert.Equal(t, "", w.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("with a result which fails to encode", func(t *testing.T) {
+		s := storeServer{rpc.NewMemoryIdempotencyStore(time.Minute)}
+		calls := 0
+		call := func() (interface{}, error) {
+			calls++
+			return map[string]interface{}{"items": make(chan int)}, nil
+		}
+
+		w := serveIdempotent(s, "a", "", call)
+		assert.Equal(t, 500, w.Code)
+		assert.Contains(t, w.Body.String(), `"type":"internal"`)
+		serveIdempotent(s, "a", "", call)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("with no content", func(t *testing.T) {
+		s := storeServer{rpc.NewMemoryIdempotencyStore(time.Minute)}
+		call := func() (interface{}, error) {
+			return nil, nil
+		}
+
+		serveIdempotent(s, "a", "", call)
+		w := serveIdempotent(s, "a", "", call)
+		assert.Equal(t, 204, w.Code)
+		assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("with a call in progress", func(t *testing.T) {
+		store := rpc.NewMemoryIdempotencyStore(time.Minute)
+		_, ok, err := store.Reserve(context.Background(), "ip 192.0.2.1 /add_item a")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		w := serveIdempotent(storeServer{store}, "a", "", func() (interface{}, error) {
+			t.Fatal("called")
+			return nil, nil
+		})
+		assert.Equal(t, 409, w.Code)
+		assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("with keys of different principals", func(t *testing.T) {
+		s := storeServer{rpc.NewMemoryIdempotencyStore(time.Minute)}
+		calls := 0
+		call := func() (interface{}, error) {
+			calls++
+			return map[string]int{"calls": calls}, nil
+		}
+
+		for _, subject := range []string{"tobi", "loki", "tobi"} {
+			r := httptest.NewRequest("POST", "/add_item", nil)
+			r = r.WithContext(rpc.NewPrincipalContext(r.Context(), &rpc.Principal{Subject: subject}))
+			r.Header.Set("Idempotency-Key", "a")
+			rpc.ServeIdempotent(httptest.NewRecorder(), r, s, call)
+		}
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("with keys of different clients", func(t *testing.T) {
+		s := storeServer{rpc.NewMemoryIdempotencyStore(time.Minute)}
+		calls := 0
+		call := func() (interface{}, error) {
+			calls++
+			return map[string]int{"calls": calls}, nil
+		}
+
+		for _, addr := range []string{"192.0.2.1:1234", "192.0.2.2:1234", "192.0.2.1:4321"} {
+			r := httptest.NewRequest("POST", "/add_item", nil)
+			r.RemoteAddr = addr
+			r.Header.Set("Idempotency-Key", "a")
+			rpc.ServeIdempotent(httptest.NewRecorder(), r, s, call)
+		}
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("with a call which panics", func(t *testing.T) {
+		s := storeServer{rpc.NewMemoryIdempotencyStore(time.Minute)}
+
+		func() {
+			defer func() {
+				assert.Equal(t, "boom", recover())
+			}()
+			serveIdempotent(s, "a", "", func() (interface{}, error) {
+				panic("boom")
+			})
+		}()
+
+		w := serveIdempotent(s, "a", "", func() (interface{}, error) {
+			return map[string]string{"name": "tobi"}, nil
+		})
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "", w.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("with a body exceeding the limits", func(t *testing.T) {
+		s := storeServer{rpc.NewMemoryIdempotencyStore(time.Minute)}
+		body := strings.NewReader(strings.Repeat(" ", 1<<20))
+		r := httptest.NewRequest("POST", "/add_item", body)
+		r.Header.Set("Idempotency-Key", "a")
+		w := httptest.NewRecorder()
+		rpc.ServeIdempotent(w, r, s, func() (interface{}, error) {
+			return nil, nil
+		}, rpc.WithLimits(rpc.Limits{MaxBytes: 10}))
+		assert.Equal(t, 204, w.Code)
+		assert.Equal(t, 1<<20-10, body.Len())
+	})
+
+	t.Run("without a key", func(t *testing.T) {
+		calls := 0
+		call := func() (interface{}, error) {
+			calls++
+			return nil, nil
+		}
+
+		for i := 0; i < 2; i++ {
+			r := httptest.NewRequest("POST", "/add_item", nil)
+			w := httptest.NewRecorder()
+			rpc.ServeIdempotent(w, r, struct{}{}, call)
+			assert.Equal(t, http.StatusNoContent, w.Code)
+		}
+		assert.Equal(t, 2, calls)
+	})
+}
+
+// Test the in-memory store.
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	s := rpc.NewMemoryIdempotencyStore(time.Minute)
+
+	_, ok, _ := s.Reserve(ctx, "a")
+	assert.True(t, ok)
+
+	res, ok, _ := s.Reserve(ctx, "a")
+	assert.False(t, ok)
+	assert.Nil(t, res)
+
+	s.Save(ctx, "a", rpc.IdempotentResponse{Status: 200})
+	res, ok, _ = s.Reserve(ctx, "a")
+	assert.False(t, ok)
+	assert.Equal(t, 200, res.Status)
+
+	s.Release(ctx, "a")
+	_, ok, _ = s.Reserve(ctx, "a")
+	assert.True(t, ok)
+
+	t.Run("with too many entries", func(t *testing.T) {
+		s := rpc.NewMemoryIdempotencyStoreSize(time.Minute, 2, 0)
+		for _, key := range []string{"a", "b", "c"} {
+			s.Reserve(ctx, key)
+			s.Save(ctx, key, rpc.IdempotentResponse{Status: 200})
+		}
+
+		_, ok, _ := s.Reserve(ctx, "a")
+		assert.True(t, ok, "oldest evicted")
+
+		_, ok, _ = s.Reserve(ctx, "c")
+		assert.False(t, ok)
+	})
+
+	t.Run("with too many bytes", func(t *testing.T) {
+		s := rpc.NewMemoryIdempotencyStoreSize(time.Minute, 0, 100)
+		s.Reserve(ctx, "a")
+		s.Save(ctx, "a", rpc.IdempotentResponse{Status: 200, Body: make([]byte, 60)})
+		s.Reserve(ctx, "b")
+		s.Save(ctx, "b", rpc.IdempotentResponse{Status: 200, Body: make([]byte, 60)})
+
+		_, ok, _ := s.Reserve(ctx, "a")
+		assert.True(t, ok, "oldest evicted")
+
+		res, ok, _ := s.Reserve(ctx, "b")
+		assert.False(t, ok)
+		assert.Len(t, res.Body, 60)
+	})
+
+	t.Run("with calls in progress", func(t *testing.T) {
+		s := rpc.NewMemoryIdempotencyStoreSize(time.Minute, 2, 0)
+		s.Reserve(ctx, "a")
+		for _, key := range []string{"b", "c"} {
+			s.Reserve(ctx, key)
+			s.Save(ctx, key, rpc.IdempotentResponse{Status: 200})
+		}
+
+		res, ok, _ := s.Reserve(ctx, "a")
+		assert.False(t, ok, "in progress kept")
+		assert.Nil(t, res)
+
+		_, ok, _ = s.Reserve(ctx, "b")
+		assert.True(t, ok, "oldest response evicted")
+
+		_, _, err := s.Reserve(ctx, "d")
+		assert.Error(t, err, "full of calls in progress")
+
+		s.Release(ctx, "a")
+		_, ok, err = s.Reserve(ctx, "d")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("with expired entries", func(t *testing.T) {
+		s := rpc.NewMemoryIdempotencyStore(time.Millisecond)
+		s.Reserve(ctx, "a")
+		time.Sleep(2 * time.Millisecond)
+		_, ok, _ := s.Reserve(ctx, "a")
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,99 @@
+package rpc
+
+import (
+	"context"
+)
+
+// MethodInfo is the schema metadata of a method.
+type MethodInfo struct {
+	// Name is the method name.
+	Name string
+
+	// Group is the method group name.
+	Group string
+
+	// Private is true for methods omitted from public clients and documentation.
+	Private bool
+
+	// Stream is true for methods streaming their outputs.
+	Stream bool
+
+	// Idempotent is true for methods honoring an Idempotency-Key.
+	Idempotent bool
+
+	// Scopes are the scopes required to call the method.
+	Scopes []string
+}
+
+// Handler is a method handler receiving the decoded input, or nil for
+// methods without inputs.
+type Handler func(ctx context.Context, in interface{}) (interface{}, error)
+
+// Interceptor is called around every method invocation with the method's
+// metadata and decoded input. It must call next to continue the chain,
+// or return without calling it to short-circuit the method.
+type Interceptor func(ctx context.Context, info MethodInfo, in interface{}, next Handler) (interface{}, error)
+
+// InterceptorProvider is the interface used for servers providing interceptors.
+type InterceptorProvider interface {
+	Interceptors() []Interceptor
+}
+
+// Chain returns an interceptor invoking interceptors in order, so that
+// the first is the outermost.
+func Chain(interceptors ...Interceptor) Interceptor {
+	return func(ctx context.Context, info MethodInfo, in interface{}, next Handler) (interface{}, error) {
+		h := next
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			h = bind(interceptors[i], info, h)
+		}
+		return h(ctx, in)
+	}
+}
+
+// bind returns a handler invoking interceptor i with next.
+func bind(i Interceptor, info MethodInfo, next Handler) Handler {
+	return func(ctx context.Context, in interface{}) (interface{}, error) {
+		return i(ctx, info, in, next)
+	}
+}
+
+// Intercept invokes h with in, through the interceptors of s when it
+// implements InterceptorProvider. Panics in h or the interceptors are
+// recovered and returned as a Panic error, see PanicReporter.
+//
+// When the deadline of ctx has passed the method is not invoked, and
+// errors returned after it passed are replaced with DeadlineExceeded.
+//
+// Each invocation is a span of the trace of ctx, exported when sampled,
+// see SpanExporterProvider.
+func Intercept(ctx context.Context, s interface{}, info MethodInfo, in interface{}, h Handler) (interface{}, error) {
+	if err := deadline(ctx); err != nil {
+		return nil, err
+	}
+
+	ctx, end := startSpan(ctx, s, info)
+	res, err := intercept(ctx, s, info, in, h)
+	if err != nil {
+		if e := deadline(ctx); e != nil {
+			res, err = nil, e
+		}
+	}
+
+	end(err)
+	return res, err
+}
+
+// intercept invokes h with in, through the interceptors of s.
+func intercept(ctx context.Context, s interface{}, info MethodInfo, in interface{}, h Handler) (interface{}, error) {
+	h = guard(s, info, h)
+
+	p, ok := s.(InterceptorProvider)
+	if !ok {
+		return h(ctx, in)
+	}
+
+	return guard(s, info, func(ctx context.Context, in interface{}) (interface{}, error) {
+		return Chain(p.Interceptors()...)(ctx, info, in, h)
+	})(ctx, in)
+}
@@ -0,0 +1,101 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// interceptedServer is a server providing interceptors.
+type interceptedServer struct {
+	interceptors []rpc.Interceptor
+}
+
+// Interceptors implementation.
+func (s interceptedServer) Interceptors() []rpc.Interceptor {
+	return s.interceptors
+}
+
+// record returns an interceptor appending name to calls.
+func record(calls *[]string, name string) rpc.Interceptor {
+	return func(ctx context.Context, info rpc.MethodInfo, in interface{}, next rpc.Handler) (interface{}, error) {
+		*calls = append(*calls, name+" "+info.Name)
+		return next(ctx, in)
+	}
+}
+
+// Test interceptors.
+func TestIntercept(t *testing.T) {
+	info := rpc.MethodInfo{Name: "add_item", Group: "items"}
+
+	handler := func(ctx context.Context, in interface{}) (interface{}, error) {
+		return in.(string) + "!", nil
+	}
+
+	t.Run("without interceptors", func(t *testing.T) {
+		res, err := rpc.Intercept(context.Background(), struct{}{}, info, "hello", handler)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello!", res)
+	})
+
+	t.Run("with interceptors", func(t *testing.T) {
+		var calls []string
+		s := interceptedServer{[]rpc.Interceptor{record(&calls, "first"), record(&calls, "second")}}
+		res, err := rpc.Intercept(context.Background(), s, info, "hello", handler)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello!", res)
+		assert.Equal(t, []string{"first add_item", "second add_item"}, calls)
+	})
+
+	t.Run("with an interceptor modifying the input", func(t *testing.T) {
+		s := interceptedServer{[]rpc.Interceptor{
+			func(ctx context.Context, info rpc.MethodInfo, in interface{}, next rpc.Handler) (interface{}, error) {
+				return next(ctx, "bye")
+			},
+		}}
+		res, err := rpc.Intercept(context.Background(), s, info, "hello", handler)
+		assert.NoError(t, err)
+		assert.Equal(t, "bye!", res)
+	})
+
+	t.Run("with an interceptor short-circuiting", func(t *testing.T) {
+		var calls []string
+		s := interceptedServer{[]rpc.Interceptor{
+			func(ctx context.Context, info rpc.MethodInfo, in interface{}, next rpc.Handler) (interface{}, error) {
+				return nil, errors.New("denied")
+			},
+			record(&calls, "second"),
+		}}
+		_, err := rpc.Intercept(context.Background(), s, info, "hello", handler)
+		assert.EqualError(t, err, "denied")
+		assert.Empty(t, calls)
+	})
+
+	t.Run("with an expired deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 0)
+		defer cancel()
+		called := false
+		_, err := rpc.Intercept(ctx, struct{}{}, info, "hello", func(ctx context.Context, in interface{}) (interface{}, error) {
+			called = true
+			return nil, nil
+		})
+		assert.False(t, called)
+		assert.Equal(t, "deadline_exceeded", err.(rpc.TypeProvider).Type())
+		assert.Equal(t, 504, err.(rpc.StatusProvider).StatusCode())
+	})
+
+	t.Run("with a deadline exceeded by the method", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := rpc.Intercept(ctx, struct{}{}, info, "hello", func(ctx context.Context, in interface{}) (interface{}, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		assert.Equal(t, "deadline_exceeded", err.(rpc.TypeProvider).Type())
+	})
+}
@@ -50,6 +50,14 @@ func GoInputType(types, method string) string {
 	return fmt.Sprintf("%s.%sInput", types, GoName(method))
 }
 
+// GoOutputType returns the name of a method output type
+func GoOutputType(types, method string) string {
+	if len(types) == 0 {
+		return fmt.Sprintf("%sOutput", GoName(method))
+	}
+	return fmt.Sprintf("%s.%sOutput", types, GoName(method))
+}
+
 // JsName returns a name formatted for JS.
 func JsName(s string) string {
 	return strcase.ToLowerCamel(s)
@@ -60,6 +68,20 @@ func RustName(s string) string {
 	return strcase.ToSnake(s)
 }
 
+// Scopes returns a sentence describing the scopes required by a method, or
+// an empty string when there are none.
+func Scopes(scopes []string) string {
+	switch len(scopes) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("Requires the %s scope.", scopes[0])
+	default:
+		last := len(scopes) - 1
+		return fmt.Sprintf("Requires the %s and %s scopes.", strings.Join(scopes[:last], ", "), scopes[last])
+	}
+}
+
 // ID returns the id case for anchors.
 func ID(s string) string {
 	return strcase.ToSnake(s)
@@ -26,3 +26,10 @@ func TestJsName(t *testing.T) {
 	assert.Equal(t, "updateUserSettings", format.JsName("update_user_settings"))
 	assert.Equal(t, "userId", format.JsName("user_id"))
 }
+
+// Test scope descriptions.
+func TestScopes(t *testing.T) {
+	assert.Equal(t, "", format.Scopes(nil))
+	assert.Equal(t, "Requires the items:write scope.", format.Scopes([]string{"items:write"}))
+	assert.Equal(t, "Requires the a, b and c scopes.", format.Scopes([]string{"a", "b", "c"}))
+}
@@ -20,6 +20,18 @@ func ResolveRef(s *schema.Schema, ref schema.Ref) schema.Type {
 	panic(fmt.Sprintf("reference to undefined type %q", ref.Value))
 }
 
+// ResolveErrors returns the errors which method m may return, or panics.
+func ResolveErrors(s *schema.Schema, m schema.Method) (v []schema.Error) {
+	for _, name := range m.Errors {
+		e, ok := s.Errors[name]
+		if !ok {
+			panic(fmt.Sprintf("reference to undefined error %q", name))
+		}
+		v = append(v, e)
+	}
+	return
+}
+
 // FormatExtra .
 func FormatExtra(f schema.Field) string {
 	return FormatAttributes(f) + FormatEnum(f)
@@ -0,0 +1,298 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// Limits are the structural limits applied to request bodies. A zero value
+// field means no limit, or when used as a method override, the default.
+type Limits struct {
+	// MaxBytes is the maximum size of the decompressed body in bytes.
+	MaxBytes int64
+
+	// MaxDepth is the maximum nesting depth of objects and arrays.
+	MaxDepth int
+
+	// MaxArrayLength is the maximum number of elements in any array.
+	MaxArrayLength int
+}
+
+// DefaultLimits are the limits applied to all requests.
+var DefaultLimits = Limits{
+	MaxBytes:       10 << 20,
+	MaxDepth:       32,
+	MaxArrayLength: 100000,
+}
+
+// merge returns l with zero fields populated from defaults.
+func (l Limits) merge(defaults Limits) Limits {
+	if l.MaxBytes == 0 {
+		l.MaxBytes = defaults.MaxBytes
+	}
+
+	if l.MaxDepth == 0 {
+		l.MaxDepth = defaults.MaxDepth
+	}
+
+	if l.MaxArrayLength == 0 {
+		l.MaxArrayLength = defaults.MaxArrayLength
+	}
+
+	return l
+}
+
+// LimitChecker is implemented by codecs which can check the structure
+// of an encoded body against the limits before it is decoded.
+type LimitChecker interface {
+	CheckLimits(b []byte, l Limits) error
+}
+
+// errTooLarge returns the error for a body exceeding l.MaxBytes.
+func errTooLarge(l Limits) error {
+	return RequestTooLarge(fmt.Sprintf("Request body must not exceed %d bytes", l.MaxBytes))
+}
+
+// errTooDeep returns the error for a body exceeding l.MaxDepth.
+func errTooDeep(l Limits) error {
+	return TooDeep(fmt.Sprintf("Request body must not be nested more than %d levels deep", l.MaxDepth))
+}
+
+// errTooLong returns the error for an array exceeding l.MaxArrayLength.
+func errTooLong(l Limits) error {
+	return RequestTooLarge(fmt.Sprintf("Request body arrays must not exceed %d elements", l.MaxArrayLength))
+}
+
+// CheckLimits implementation.
+func (jsonCodec) CheckLimits(b []byte, l Limits) error {
+	// counts holds the number of commas seen for each open
+	// array, or -1 for objects, which are not limited
+	var stack [16]int
+	counts := stack[:0]
+
+	for i := 0; i < len(b); i++ {
+		if !jsonStructural[b[i]] {
+			continue
+		}
+
+		switch c := b[i]; c {
+		case '"':
+			i = stringEnd(b, i)
+		case '{', '[':
+			if l.MaxDepth > 0 && len(counts) >= l.MaxDepth {
+				return errTooDeep(l)
+			}
+			if c == '{' {
+				counts = append(counts, -1)
+			} else {
+				counts = append(counts, 0)
+			}
+		case '}', ']':
+			if len(counts) > 0 {
+				counts = counts[:len(counts)-1]
+			}
+		case ',':
+			if n := len(counts) - 1; n >= 0 && counts[n] >= 0 {
+				counts[n]++
+				if l.MaxArrayLength > 0 && counts[n]+1 > l.MaxArrayLength {
+					return errTooLong(l)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// jsonStructural holds the bytes of JSON strings and containers checked against the limits.
+var jsonStructural = [256]bool{'"': true, '{': true, '[': true, '}': true, ']': true, ',': true}
+
+// stringEnd returns the index of the quote closing the JSON string opened
+// at i, or len(b) when it is not closed.
+func stringEnd(b []byte, i int) int {
+	for i++; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return len(b)
+}
+
+// CheckLimits implementation.
+func (msgpackCodec) CheckLimits(b []byte, l Limits) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(b))
+	return checkMsgpack(dec, l, 0)
+}
+
+// checkMsgpack checks the next MessagePack value against the limits. Malformed
+// input is ignored as it is reported when decoding.
+func checkMsgpack(dec *msgpack.Decoder, l Limits, depth int) error {
+	c, err := dec.PeekCode()
+	if err != nil {
+		return nil
+	}
+
+	switch {
+	case msgpcode.IsFixedArray(c) || c == msgpcode.Array16 || c == msgpcode.Array32:
+		if l.MaxDepth > 0 && depth >= l.MaxDepth {
+			return errTooDeep(l)
+		}
+
+		n, err := dec.DecodeArrayLen()
+		if err != nil {
+			return nil
+		}
+
+		if l.MaxArrayLength > 0 && n > l.MaxArrayLength {
+			return errTooLong(l)
+		}
+
+		for i := 0; i < n; i++ {
+			if err := checkMsgpack(dec, l, depth+1); err != nil {
+				return err
+			}
+		}
+	case msgpcode.IsFixedMap(c) || c == msgpcode.Map16 || c == msgpcode.Map32:
+		if l.MaxDepth > 0 && depth >= l.MaxDepth {
+			return errTooDeep(l)
+		}
+
+		n, err := dec.DecodeMapLen()
+		if err != nil {
+			return nil
+		}
+
+		for i := 0; i < n*2; i++ {
+			if err := checkMsgpack(dec, l, depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		dec.Skip()
+	}
+
+	return nil
+}
+
+// CheckLimits implementation.
+func (cborCodec) CheckLimits(b []byte, l Limits) error {
+	_, err := checkCBOR(b, l, 0)
+	return err
+}
+
+// cborBreak is the stop code of indefinite-length items.
+const cborBreak = 0xff
+
+// checkCBOR checks the CBOR value at the start of b against the limits,
+// returning the remaining bytes. Malformed input is ignored as it is
+// reported when decoding.
+func checkCBOR(b []byte, l Limits, depth int) ([]byte, error) {
+	var major byte
+	var n uint64
+	var indefinite bool
+
+	// head, skipping the tags of the value without recursing
+	for major = 6; major == 6; {
+		if len(b) == 0 {
+			return nil, nil
+		}
+
+		major = b[0] >> 5
+		info := b[0] & 0x1f
+		b = b[1:]
+
+		// argument
+		indefinite = info == 31
+		switch {
+		case info < 24:
+			n = uint64(info)
+		case info == 24 && len(b) >= 1:
+			n, b = uint64(b[0]), b[1:]
+		case info == 25 && len(b) >= 2:
+			n, b = uint64(binary.BigEndian.Uint16(b)), b[2:]
+		case info == 26 && len(b) >= 4:
+			n, b = uint64(binary.BigEndian.Uint32(b)), b[4:]
+		case info == 27 && len(b) >= 8:
+			n, b = binary.BigEndian.Uint64(b), b[8:]
+		case indefinite:
+		default:
+			return nil, nil
+		}
+	}
+
+	switch major {
+	case 2, 3: // byte and text strings
+		if indefinite {
+			for len(b) > 0 && b[0] != cborBreak {
+				// chunks must be definite strings
+				if b[0]&0x1f == 31 {
+					return nil, nil
+				}
+
+				var err error
+				if b, err = checkCBOR(b, l, depth); err != nil {
+					return nil, err
+				}
+			}
+			return skipBreak(b), nil
+		}
+		if n > uint64(len(b)) {
+			return nil, nil
+		}
+		return b[n:], nil
+	case 4, 5: // arrays and maps
+		if l.MaxDepth > 0 && depth >= l.MaxDepth {
+			return nil, errTooDeep(l)
+		}
+
+		items := n
+		if major == 5 {
+			items *= 2
+		}
+
+		if indefinite {
+			var count int
+			for len(b) > 0 && b[0] != cborBreak {
+				count++
+				if major == 4 && l.MaxArrayLength > 0 && count > l.MaxArrayLength {
+					return nil, errTooLong(l)
+				}
+
+				var err error
+				if b, err = checkCBOR(b, l, depth+1); err != nil {
+					return nil, err
+				}
+			}
+			return skipBreak(b), nil
+		}
+
+		if major == 4 && l.MaxArrayLength > 0 && n > uint64(l.MaxArrayLength) {
+			return nil, errTooLong(l)
+		}
+
+		for i := uint64(0); i < items && len(b) > 0; i++ {
+			var err error
+			if b, err = checkCBOR(b, l, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default: // integers, floats and simple values
+		return b, nil
+	}
+}
+
+// skipBreak returns b without its leading stop code.
+func skipBreak(b []byte) []byte {
+	if len(b) > 0 {
+		return b[1:]
+	}
+	return b
+}
@@ -0,0 +1,137 @@
+package rpc_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// Test request limits.
+func TestReadRequest_limits(t *testing.T) {
+	limits := rpc.WithLimits(rpc.Limits{
+		MaxBytes:       64,
+		MaxDepth:       3,
+		MaxArrayLength: 3,
+	})
+
+	t.Run("with a Content-Length exceeding the limit", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "name": "`+strings.Repeat("x", 64)+`" }`))
+		r.Header.Set("Content-Type", "application/json")
+		var in struct{ Name string }
+		err := rpc.ReadRequest(r, &in, limits)
+		assert.EqualError(t, err, `Request body must not exceed 64 bytes`)
+		assert.Equal(t, 413, err.(rpc.StatusProvider).StatusCode())
+		assert.Equal(t, "request_too_large", err.(rpc.TypeProvider).Type())
+	})
+
+	t.Run("with a body exceeding the limit", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", ioutil.NopCloser(strings.NewReader(`{ "name": "`+strings.Repeat("x", 64)+`" }`)))
+		r.ContentLength = -1
+		r.Header.Set("Content-Type", "application/json")
+		var in struct{ Name string }
+		err := rpc.ReadRequest(r, &in, limits)
+		assert.EqualError(t, err, `Request body must not exceed 64 bytes`)
+	})
+
+	t.Run("with a compressed body exceeding the limit", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", compress(t, rpc.Gzip, `{ "name": "`+strings.Repeat("x", 64)+`" }`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Content-Encoding", "gzip")
+		var in struct{ Name string }
+		err := rpc.ReadRequest(r, &in, limits)
+		assert.EqualError(t, err, `Request body must not exceed 64 bytes`)
+	})
+
+	t.Run("with JSON nested too deep", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "a": [{ "b": [] }] }`))
+		r.Header.Set("Content-Type", "application/json")
+		var in map[string]interface{}
+		err := rpc.ReadRequest(r, &in, limits)
+		assert.EqualError(t, err, `Request body must not be nested more than 3 levels deep`)
+		assert.Equal(t, 400, err.(rpc.StatusProvider).StatusCode())
+		assert.Equal(t, "too_deep", err.(rpc.TypeProvider).Type())
+	})
+
+	t.Run("with a JSON array too long", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "a": [1, 2, 3, 4] }`))
+		r.Header.Set("Content-Type", "application/json")
+		var in map[string]interface{}
+		err := rpc.ReadRequest(r, &in, limits)
+		assert.EqualError(t, err, `Request body arrays must not exceed 3 elements`)
+	})
+
+	t.Run("with JSON strings containing delimiters", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "a": ["[[[,,,", "\"{{"] }`))
+		r.Header.Set("Content-Type", "application/json")
+		var in map[string]interface{}
+		err := rpc.ReadRequest(r, &in, limits)
+		assert.NoError(t, err)
+	})
+
+	for _, c := range []rpc.Codec{rpc.MessagePack, rpc.CBOR} {
+		t.Run(c.Name(), func(t *testing.T) {
+			t.Run("nested too deep", func(t *testing.T) {
+				var buf bytes.Buffer
+				assert.NoError(t, c.Encode(&buf, map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": []int{}}}}))
+				r := httptest.NewRequest("POST", "/", &buf)
+				r.Header.Set("Content-Type", c.ContentType())
+				var in map[string]interface{}
+				err := rpc.ReadRequest(r, &in, limits)
+				assert.EqualError(t, err, `Request body must not be nested more than 3 levels deep`)
+			})
+
+			t.Run("with an array too long", func(t *testing.T) {
+				var buf bytes.Buffer
+				assert.NoError(t, c.Encode(&buf, map[string]interface{}{"a": []int{1, 2, 3, 4}}))
+				r := httptest.NewRequest("POST", "/", &buf)
+				r.Header.Set("Content-Type", c.ContentType())
+				var in map[string]interface{}
+				err := rpc.ReadRequest(r, &in, limits)
+				assert.EqualError(t, err, `Request body arrays must not exceed 3 elements`)
+			})
+
+			t.Run("within the limits", func(t *testing.T) {
+				var buf bytes.Buffer
+				assert.NoError(t, c.Encode(&buf, map[string]interface{}{"a": []string{"x", "y", "z"}}))
+				r := httptest.NewRequest("POST", "/", &buf)
+				r.Header.Set("Content-Type", c.ContentType())
+				var in map[string]interface{}
+				err := rpc.ReadRequest(r, &in, limits)
+				assert.NoError(t, err)
+			})
+		})
+	}
+
+	t.Run("with a long run of CBOR tags", func(t *testing.T) {
+		b := bytes.Repeat([]byte{0xc0}, 8<<20)
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+		r.Header.Set("Content-Type", rpc.CBOR.ContentType())
+		var in map[string]interface{}
+		err := rpc.ReadRequest(r, &in, rpc.WithLimits(rpc.Limits{MaxBytes: 16 << 20}))
+		assert.Error(t, err)
+	})
+
+	t.Run("with CBOR tags around nested arrays", func(t *testing.T) {
+		b := []byte{0xa1, 0x61, 'a', 0xc0, 0xc0, 0x81, 0xc0, 0x81, 0xc0, 0x81, 0x01}
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+		r.Header.Set("Content-Type", rpc.CBOR.ContentType())
+		var in map[string]interface{}
+		err := rpc.ReadRequest(r, &in, limits)
+		assert.EqualError(t, err, `Request body must not be nested more than 3 levels deep`)
+	})
+
+	t.Run("with nested indefinite CBOR strings", func(t *testing.T) {
+		b := bytes.Repeat([]byte{0x5f}, 1<<20)
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+		r.Header.Set("Content-Type", rpc.CBOR.ContentType())
+		var in map[string]interface{}
+		err := rpc.ReadRequest(r, &in, rpc.WithLimits(rpc.Limits{MaxBytes: 16 << 20}))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,46 @@
+package rpc
+
+import (
+	"net/http"
+)
+
+// MetricsRecorder is the interface used for recording method metrics served
+// at /_metrics, such as a metrics.Registry.
+type MetricsRecorder interface {
+	http.Handler
+	Begin(path string) func(err error)
+}
+
+// MetricsProvider is the interface used for servers recording method metrics.
+type MetricsProvider interface {
+	Metrics() MetricsRecorder
+}
+
+// metricsRecorder returns the recorder provided by s, or nil.
+func metricsRecorder(s interface{}) MetricsRecorder {
+	if p, ok := s.(MetricsProvider); ok {
+		return p.Metrics()
+	}
+	return nil
+}
+
+// BeginMetrics records the start of a call to the method at path with the
+// recorder provided by s, if any, returning a function recording its end.
+func BeginMetrics(s interface{}, path string) func(err error) {
+	m := metricsRecorder(s)
+	if m == nil {
+		return func(error) {}
+	}
+	return m.Begin(path)
+}
+
+// ServeMetrics serves the metrics recorded by the recorder provided by s,
+// returning false when s does not provide one.
+func ServeMetrics(w http.ResponseWriter, r *http.Request, s interface{}) bool {
+	m := metricsRecorder(s)
+	if m == nil {
+		return false
+	}
+	m.ServeHTTP(w, r)
+	return true
+}
@@ -0,0 +1,202 @@
+// Package metrics provides method metrics in the Prometheus text format.
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/rpc"
+)
+
+// Unknown is the method label of calls to methods which are not registered.
+const Unknown = "unknown"
+
+// Buckets are the upper bounds of the latency histogram buckets in seconds.
+var Buckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry records the metrics of calls, labeled by method name.
+type Registry struct {
+	methods map[string]*method
+}
+
+// method is the metrics of a method.
+type method struct {
+	mu       sync.Mutex
+	calls    uint64
+	errors   map[string]uint64
+	inflight int64
+	buckets  []uint64
+	sum      float64
+}
+
+// New returns a new registry for the given method names, typically those of
+// the schema, so that the cardinality of the method label is bounded.
+func New(methods ...string) *Registry {
+	r := &Registry{
+		methods: make(map[string]*method),
+	}
+
+	r.methods[Unknown] = newMethod()
+	for _, name := range methods {
+		r.methods[name] = newMethod()
+	}
+
+	return r
+}
+
+// newMethod returns new method metrics.
+func newMethod() *method {
+	return &method{
+		errors:  make(map[string]uint64),
+		buckets: make([]uint64, len(Buckets)),
+	}
+}
+
+// Begin records the start of a call to the method at path, returning a
+// function which records its completion with the error returned, if any.
+// Paths of methods which are not registered are recorded as Unknown.
+func (r *Registry) Begin(path string) func(err error) {
+	m, ok := r.methods[strings.TrimPrefix(path, "/")]
+	if !ok {
+		m = r.methods[Unknown]
+	}
+
+	start := time.Now()
+	m.mu.Lock()
+	m.inflight++
+	m.mu.Unlock()
+
+	return func(err error) {
+		d := time.Since(start).Seconds()
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		m.inflight--
+		m.calls++
+		m.sum += d
+
+		for i, le := range Buckets {
+			if d <= le {
+				m.buckets[i]++
+			}
+		}
+
+		if err != nil {
+			m.errors[errorType(err)]++
+		}
+	}
+}
+
+// errorType returns the type of err as written by rpc.WriteError.
+func errorType(err error) string {
+	if e := rpc.TypeProvider(nil); errors.As(err, &e) {
+		return e.Type()
+	}
+	return "internal"
+}
+
+// ServeHTTP implementation.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format to w.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	out := func(format string, v ...interface{}) {
+		fmt.Fprintf(&b, format, v...)
+	}
+
+	var names []string
+	snapshots := make(map[string]sample)
+	for name, m := range r.methods {
+		names = append(names, name)
+		snapshots[name] = m.snapshot()
+	}
+	sort.Strings(names)
+
+	out("# HELP rpc_requests_total The number of completed calls.\n")
+	out("# TYPE rpc_requests_total counter\n")
+	for _, name := range names {
+		out("rpc_requests_total{method=\"%s\"} %d\n", escape(name), snapshots[name].calls)
+	}
+
+	out("# HELP rpc_errors_total The number of calls which failed, by error type.\n")
+	out("# TYPE rpc_errors_total counter\n")
+	for _, name := range names {
+		m := snapshots[name]
+		var types []string
+		for kind := range m.errors {
+			types = append(types, kind)
+		}
+		sort.Strings(types)
+		for _, kind := range types {
+			out("rpc_errors_total{method=\"%s\",type=\"%s\"} %d\n", escape(name), escape(kind), m.errors[kind])
+		}
+	}
+
+	out("# HELP rpc_requests_in_flight The number of calls in progress.\n")
+	out("# TYPE rpc_requests_in_flight gauge\n")
+	for _, name := range names {
+		out("rpc_requests_in_flight{method=\"%s\"} %d\n", escape(name), snapshots[name].inflight)
+	}
+
+	out("# HELP rpc_request_duration_seconds The latency of completed calls.\n")
+	out("# TYPE rpc_request_duration_seconds histogram\n")
+	for _, name := range names {
+		m := snapshots[name]
+		for i, le := range Buckets {
+			out("rpc_request_duration_seconds_bucket{method=\"%s\",le=\"%g\"} %d\n", escape(name), le, m.buckets[i])
+		}
+		out("rpc_request_duration_seconds_bucket{method=\"%s\",le=\"+Inf\"} %d\n", escape(name), m.calls)
+		out("rpc_request_duration_seconds_sum{method=\"%s\"} %g\n", escape(name), m.sum)
+		out("rpc_request_duration_seconds_count{method=\"%s\"} %d\n", escape(name), m.calls)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// sample is a copy of the metrics of a method.
+type sample struct {
+	calls    uint64
+	errors   map[string]uint64
+	inflight int64
+	buckets  []uint64
+	sum      float64
+}
+
+// snapshot returns a copy of the metrics.
+func (m *method) snapshot() sample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	errors := make(map[string]uint64, len(m.errors))
+	for k, v := range m.errors {
+		errors[k] = v
+	}
+
+	return sample{
+		calls:    m.calls,
+		errors:   errors,
+		inflight: m.inflight,
+		buckets:  append([]uint64(nil), m.buckets...),
+		sum:      m.sum,
+	}
+}
+
+// escaper escapes label values.
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escape returns the label value s escaped.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
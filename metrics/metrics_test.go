@@ -0,0 +1,55 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+	"github.com/apex/rpc/metrics"
+)
+
+// Test recording calls.
+func TestRegistry_Begin(t *testing.T) {
+	r := metrics.New("add_item", "get_items")
+
+	r.Begin("/add_item")(nil)
+	r.Begin("/add_item")(rpc.BadRequest("Invalid"))
+	r.Begin("/add_item")(errors.New("boom"))
+	r.Begin("/nope")(nil)
+	r.Begin("/nope/../nope")(nil)
+	done := r.Begin("/get_items")
+
+	var b strings.Builder
+	_, err := r.WriteTo(&b)
+	assert.NoError(t, err)
+	s := b.String()
+
+	assert.Contains(t, s, `rpc_requests_total{method="add_item"} 3`+"\n")
+	assert.Contains(t, s, `rpc_requests_total{method="get_items"} 0`+"\n")
+	assert.Contains(t, s, `rpc_requests_total{method="unknown"} 2`+"\n")
+	assert.Contains(t, s, `rpc_errors_total{method="add_item",type="bad_request"} 1`+"\n")
+	assert.Contains(t, s, `rpc_errors_total{method="add_item",type="internal"} 1`+"\n")
+	assert.Contains(t, s, `rpc_requests_in_flight{method="get_items"} 1`+"\n")
+	assert.Contains(t, s, `rpc_requests_in_flight{method="add_item"} 0`+"\n")
+	assert.Contains(t, s, `rpc_request_duration_seconds_bucket{method="add_item",le="0.005"} 3`+"\n")
+	assert.Contains(t, s, `rpc_request_duration_seconds_bucket{method="add_item",le="+Inf"} 3`+"\n")
+	assert.Contains(t, s, `rpc_request_duration_seconds_count{method="add_item"} 3`+"\n")
+	assert.Contains(t, s, "# TYPE rpc_request_duration_seconds histogram\n")
+
+	done(nil)
+}
+
+// Test serving metrics.
+func TestRegistry_ServeHTTP(t *testing.T) {
+	r := metrics.New("add_item")
+	r.Begin("/add_item")(rpc.Error(400, "a\"b", "Invalid"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/_metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `rpc_errors_total{method="add_item",type="a\"b"} 1`)
+}
@@ -0,0 +1,70 @@
+package rpc_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// recorder is a metrics recorder of the calls made.
+type recorder struct {
+	calls []string
+}
+
+// Begin implementation.
+func (r *recorder) Begin(path string) func(err error) {
+	return func(err error) {
+		r.calls = append(r.calls, path)
+	}
+}
+
+// ServeHTTP implementation.
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Write([]byte("metrics"))
+}
+
+// metricsServer is a server providing a metrics recorder.
+type metricsServer struct {
+	recorder *recorder
+}
+
+// Metrics implementation.
+func (s metricsServer) Metrics() rpc.MetricsRecorder {
+	return s.recorder
+}
+
+// Test recording metrics.
+func TestBeginMetrics(t *testing.T) {
+	t.Run("with a provider", func(t *testing.T) {
+		s := metricsServer{&recorder{}}
+		rpc.BeginMetrics(s, "/get_items")(nil)
+		rpc.BeginMetrics(s, "/add_item")(errors.New("boom"))
+		assert.Equal(t, []string{"/get_items", "/add_item"}, s.recorder.calls)
+	})
+
+	t.Run("without a provider", func(t *testing.T) {
+		rpc.BeginMetrics(struct{}{}, "/get_items")(nil)
+	})
+}
+
+// Test serving metrics.
+func TestServeMetrics(t *testing.T) {
+	t.Run("with a provider", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/_metrics", nil)
+		w := httptest.NewRecorder()
+		assert.True(t, rpc.ServeMetrics(w, r, metricsServer{&recorder{}}))
+		assert.Equal(t, "metrics", w.Body.String())
+	})
+
+	t.Run("without a provider", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/_metrics", nil)
+		w := httptest.NewRecorder()
+		assert.False(t, rpc.ServeMetrics(w, r, struct{}{}))
+		assert.Equal(t, 0, w.Body.Len())
+	})
+}
@@ -0,0 +1,106 @@
+package rpc
+
+import (
+	stdjson "encoding/json"
+	"net/url"
+	"reflect"
+	"strconv"
+)
+
+// QueryBody returns the JSON body of the query-string parameters q, converting
+// each parameter to the type of the field of value it names. Numbers and
+// booleans are converted when they parse, repeated parameters form arrays,
+// and objects are passed as JSON. Other values, including those of unknown
+// fields, are strings, so they are reported by ReadQuery as invalid.
+//
+// The "pretty" parameter is reserved for responses and is ignored.
+func QueryBody(q url.Values, value interface{}) ([]byte, error) {
+	t := reflect.TypeOf(value)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var fields map[string]reflect.StructField
+	if t != nil && t.Kind() == reflect.Struct {
+		fields = structFields(t)
+	}
+
+	body := make(map[string]interface{}, len(q))
+	for key, values := range q {
+		if key == "pretty" || len(values) == 0 {
+			continue
+		}
+
+		f, ok := lookupField(fields, key)
+		if !ok {
+			body[key] = values[len(values)-1]
+			continue
+		}
+
+		body[key] = queryValue(values, f.Type)
+	}
+
+	return json.Marshal(body)
+}
+
+// queryValue returns the values of a query-string parameter converted to t.
+func queryValue(values []string, t reflect.Type) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// types decoding themselves from strings
+	if t == timeType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return values[len(values)-1]
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		// bytes are encoded as base64 strings
+		if t.Elem().Kind() == reflect.Uint8 {
+			return values[len(values)-1]
+		}
+		items := make([]interface{}, len(values))
+		for i, v := range values {
+			items[i] = queryScalar(v, t.Elem())
+		}
+		return items
+	default:
+		return queryScalar(values[len(values)-1], t)
+	}
+}
+
+// queryScalar returns the query-string value s converted to t, or s when it
+// does not parse.
+func queryScalar(s string, t reflect.Type) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		// a parameter without value is true, as in "?done"
+		if s == "" {
+			return true
+		}
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if _, err := strconv.ParseFloat(s, 64); err == nil && stdjson.Valid([]byte(s)) {
+			return stdjson.Number(s)
+		}
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+		if stdjson.Valid([]byte(s)) {
+			return stdjson.RawMessage(s)
+		}
+	}
+
+	return s
+}
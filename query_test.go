@@ -0,0 +1,84 @@
+package rpc_test
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// queryInput is an input read from query-strings.
+type queryInput struct {
+	Name      string            `json:"name"`
+	Limit     int               `json:"limit"`
+	Ratio     float64           `json:"ratio"`
+	Done      bool              `json:"done"`
+	IDs       []int             `json:"ids"`
+	Tags      []string          `json:"tags"`
+	Filter    map[string]string `json:"filter"`
+	CreatedAt time.Time         `json:"created_at"`
+	Cursor    *string           `json:"cursor"`
+}
+
+// Test query-string inputs.
+func TestReadQuery(t *testing.T) {
+	read := func(query string, options ...rpc.ReadOption) (queryInput, error) {
+		r := httptest.NewRequest("GET", "/get_items?"+query, nil)
+		var in queryInput
+		err := rpc.ReadQuery(r, &in, options...)
+		return in, err
+	}
+
+	t.Run("without parameters", func(t *testing.T) {
+		in, err := read("")
+		assert.NoError(t, err)
+		assert.Equal(t, queryInput{}, in)
+	})
+
+	t.Run("with typed parameters", func(t *testing.T) {
+		in, err := read(`name=Tobi&limit=10&ratio=0.5&done&ids=1&ids=2&tags=a&filter={"color":"brown"}&created_at=2020-01-01T00:00:00Z&cursor=abc&pretty`)
+		assert.NoError(t, err)
+		assert.Equal(t, "Tobi", in.Name)
+		assert.Equal(t, 10, in.Limit)
+		assert.Equal(t, 0.5, in.Ratio)
+		assert.True(t, in.Done)
+		assert.Equal(t, []int{1, 2}, in.IDs)
+		assert.Equal(t, []string{"a"}, in.Tags)
+		assert.Equal(t, map[string]string{"color": "brown"}, in.Filter)
+		assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), in.CreatedAt)
+		assert.Equal(t, "abc", *in.Cursor)
+	})
+
+	t.Run("with numeric strings", func(t *testing.T) {
+		in, err := read("name=123&done=false")
+		assert.NoError(t, err)
+		assert.Equal(t, "123", in.Name)
+		assert.False(t, in.Done)
+	})
+
+	t.Run("with invalid parameters", func(t *testing.T) {
+		_, err := read("limit=ten&done=maybe&ids=1&ids=two")
+		assert.EqualError(t, err, `done must be of type boolean, /ids/1 must be of type integer, limit must be of type integer`)
+	})
+
+	t.Run("with unknown parameters in strict mode", func(t *testing.T) {
+		_, err := read("name=Tobi&nme=Tobi&pretty", rpc.WithStrict())
+		assert.EqualError(t, err, `Unknown field /nme`)
+	})
+}
+
+// Test query-string bodies.
+func TestQueryBody(t *testing.T) {
+	q := url.Values{
+		"limit": {"Inf"},
+		"other": {"1"},
+	}
+
+	b, err := rpc.QueryBody(q, &queryInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"limit":"Inf","other":"1"}`, string(b))
+}
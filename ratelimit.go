@@ -0,0 +1,206 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit is the rate limit of a method.
+type RateLimit struct {
+	// Rate is the number of calls per second allowed for each caller.
+	Rate float64
+
+	// Burst is the number of calls allowed at once for each caller,
+	// defaulting to the rate rounded up.
+	Burst int
+
+	// Concurrency is the maximum number of calls in progress across all
+	// callers, shedding load beyond it.
+	Concurrency int
+}
+
+// burst returns the bucket size of the limit.
+func (l RateLimit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return int(math.Ceil(l.Rate))
+}
+
+// RateLimiterProvider is the interface used for servers providing a rate limiter.
+type RateLimiterProvider interface {
+	RateLimiter() *RateLimiter
+}
+
+// DefaultRateLimiter is the rate limiter used by servers which do not provide one.
+var DefaultRateLimiter = NewRateLimiter()
+
+// Throttle applies limit to the call of method, returning a function which
+// must be called when the call completes. Calls are limited with a token
+// bucket keyed by the principal of ctx, or the IP address of its request,
+// and rejected with a 429 rate_limited error providing the Retry-After and
+// RateLimit-* headers.
+//
+// The limiter is provided by s when it implements RateLimiterProvider,
+// otherwise DefaultRateLimiter is used.
+func Throttle(ctx context.Context, s interface{}, method string, limit RateLimit) (func(), error) {
+	l := DefaultRateLimiter
+	if p, ok := s.(RateLimiterProvider); ok {
+		l = p.RateLimiter()
+	}
+
+	return l.Allow(method, callerKey(ctx), limit)
+}
+
+// callerKey returns the rate limit key of the caller of ctx.
+func callerKey(ctx context.Context) string {
+	if p := PrincipalFromContext(ctx); p != nil {
+		return "principal " + p.Subject
+	}
+
+	r, ok := RequestFromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	return "ip " + remoteHost(r)
+}
+
+// remoteHost returns the host of the remote address of r.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RateLimiter is an in-memory rate limiter.
+type RateLimiter struct {
+	mu       sync.Mutex
+	swept    time.Time
+	buckets  map[string]*bucket
+	inflight map[string]int
+}
+
+// bucket is a token bucket.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// NewRateLimiter returns a new rate limiter.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets:  make(map[string]*bucket),
+		inflight: make(map[string]int),
+	}
+}
+
+// Allow applies limit to a call of method by the caller identified by key,
+// returning a function which must be called when the call completes.
+func (l *RateLimiter) Allow(method, key string, limit RateLimit) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	if limit.Concurrency > 0 && l.inflight[method] >= limit.Concurrency {
+		return nil, Error(http.StatusTooManyRequests, "rate_limited", "Too many calls in progress", WithRetryAfter(time.Second))
+	}
+
+	if limit.Rate > 0 {
+		if err := l.take(method+" "+key, limit, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if limit.Concurrency > 0 {
+		l.inflight[method]++
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if limit.Concurrency > 0 {
+				l.release(method)
+			}
+		})
+	}, nil
+}
+
+// take takes a token from the bucket of key.
+func (l *RateLimiter) take(key string, limit RateLimit, now time.Time) error {
+	burst := float64(limit.burst())
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		wait := seconds((1 - b.tokens) / limit.Rate)
+		header := rateLimitHeader(burst, seconds((burst-b.tokens)/limit.Rate))
+		msg := fmt.Sprintf("Rate limit of %g calls per second exceeded", limit.Rate)
+		return Error(http.StatusTooManyRequests, "rate_limited", msg, WithRetryAfter(time.Duration(wait)*time.Second), WithHeader(header))
+	}
+
+	b.tokens--
+	b.full = now.Add(time.Duration((burst - b.tokens) / limit.Rate * float64(time.Second)))
+	return nil
+}
+
+// release ends a call of method.
+func (l *RateLimiter) release(method string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight[method]--
+	if l.inflight[method] <= 0 {
+		delete(l.inflight, method)
+	}
+}
+
+// sweep removes buckets which have refilled, at most once per minute.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+
+	l.swept = now
+	for k, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// rateLimitHeader returns the RateLimit-* headers of an empty bucket of
+// the given size, which is full again after reset seconds.
+func rateLimitHeader(limit float64, reset int64) http.Header {
+	h := http.Header{}
+	h.Set("RateLimit-Limit", strconv.FormatInt(int64(limit), 10))
+	h.Set("RateLimit-Remaining", "0")
+	h.Set("RateLimit-Reset", strconv.FormatInt(reset, 10))
+	return h
+}
+
+// seconds returns s rounded up to whole seconds, at least one.
+func seconds(s float64) int64 {
+	if s < 1 {
+		return 1
+	}
+	return int64(math.Ceil(s))
+}
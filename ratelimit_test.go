@@ -0,0 +1,111 @@
+package rpc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// limiterServer is a server providing a rate limiter.
+type limiterServer struct {
+	limiter *rpc.RateLimiter
+}
+
+// RateLimiter implementation.
+func (s limiterServer) RateLimiter() *rpc.RateLimiter {
+	return s.limiter
+}
+
+// callerContext returns a context for a request from addr.
+func callerContext(addr string) context.Context {
+	r := httptest.NewRequest("POST", "/add_item", nil)
+	r.RemoteAddr = addr
+	return rpc.NewRequestContext(r.Context(), r)
+}
+
+// Test rate limiting.
+func TestThrottle(t *testing.T) {
+	t.Run("with a rate", func(t *testing.T) {
+		s := limiterServer{rpc.NewRateLimiter()}
+		ctx := callerContext("10.0.0.1:1234")
+		limit := rpc.RateLimit{Rate: 1, Burst: 2}
+
+		for i := 0; i < 2; i++ {
+			release, err := rpc.Throttle(ctx, s, "add_item", limit)
+			assert.NoError(t, err)
+			release()
+		}
+
+		_, err := rpc.Throttle(ctx, s, "add_item", limit)
+		assert.Error(t, err)
+
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, err)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "1", w.Header().Get("Retry-After"))
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Reset"))
+		assert.Contains(t, w.Body.String(), `"type":"rate_limited"`)
+	})
+
+	t.Run("with different callers", func(t *testing.T) {
+		s := limiterServer{rpc.NewRateLimiter()}
+		limit := rpc.RateLimit{Rate: 1}
+
+		_, err := rpc.Throttle(callerContext("10.0.0.1:1234"), s, "add_item", limit)
+		assert.NoError(t, err)
+
+		_, err = rpc.Throttle(callerContext("10.0.0.1:5678"), s, "add_item", limit)
+		assert.Error(t, err, "same ip")
+
+		_, err = rpc.Throttle(callerContext("10.0.0.2:1234"), s, "add_item", limit)
+		assert.NoError(t, err, "another ip")
+
+		ctx := rpc.NewPrincipalContext(callerContext("10.0.0.1:1234"), &rpc.Principal{Subject: "tobi"})
+		_, err = rpc.Throttle(ctx, s, "add_item", limit)
+		assert.NoError(t, err, "a principal")
+	})
+
+	t.Run("with a refilled bucket", func(t *testing.T) {
+		s := limiterServer{rpc.NewRateLimiter()}
+		ctx := callerContext("10.0.0.1:1234")
+		limit := rpc.RateLimit{Rate: 50}
+
+		for i := 0; i < 50; i++ {
+			_, err := rpc.Throttle(ctx, s, "add_item", limit)
+			assert.NoError(t, err)
+		}
+
+		_, err := rpc.Throttle(ctx, s, "add_item", limit)
+		assert.Error(t, err)
+
+		time.Sleep(50 * time.Millisecond)
+		_, err = rpc.Throttle(ctx, s, "add_item", limit)
+		assert.NoError(t, err)
+	})
+
+	t.Run("with a concurrency limit", func(t *testing.T) {
+		s := limiterServer{rpc.NewRateLimiter()}
+		limit := rpc.RateLimit{Concurrency: 1}
+
+		release, err := rpc.Throttle(callerContext("10.0.0.1:1234"), s, "watch_items", limit)
+		assert.NoError(t, err)
+
+		_, err = rpc.Throttle(callerContext("10.0.0.2:1234"), s, "watch_items", limit)
+		assert.Equal(t, http.StatusTooManyRequests, err.(rpc.StatusProvider).StatusCode())
+
+		release()
+		release()
+
+		release, err = rpc.Throttle(callerContext("10.0.0.2:1234"), s, "watch_items", limit)
+		assert.NoError(t, err)
+		release()
+	})
+}
@@ -0,0 +1,65 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+// Panic is a panic recovered from a method invocation. It is written by
+// WriteError as an internal error.
+type Panic struct {
+	// Value is the value passed to panic().
+	Value interface{}
+
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+
+	// Method is the name of the method invoked.
+	Method string
+
+	// RequestID is the id of the request, if any.
+	RequestID string
+}
+
+// Error implementation.
+func (p Panic) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// PanicReporter is the interface used for servers reporting recovered
+// panics, otherwise they are written to the standard logger.
+type PanicReporter interface {
+	ReportPanic(ctx context.Context, p Panic)
+}
+
+// recovered reports the recovered panic value v, returning it as an error.
+func recovered(ctx context.Context, s interface{}, info MethodInfo, v interface{}) error {
+	p := Panic{
+		Value:     v,
+		Stack:     debug.Stack(),
+		Method:    info.Name,
+		RequestID: RequestIDFromContext(ctx),
+	}
+
+	if r, ok := s.(PanicReporter); ok {
+		r.ReportPanic(ctx, p)
+	} else {
+		log.Printf("rpc: %s in method %s of request %s\n%s", p.Error(), p.Method, p.RequestID, p.Stack)
+	}
+
+	return p
+}
+
+// guard returns a handler recovering panics in h.
+func guard(s interface{}, info MethodInfo, h Handler) Handler {
+	return func(ctx context.Context, in interface{}) (res interface{}, err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = recovered(ctx, s, info, v)
+			}
+		}()
+		return h(ctx, in)
+	}
+}
@@ -0,0 +1,97 @@
+package rpc_test
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// reportingServer is a server reporting panics.
+type reportingServer struct {
+	interceptedServer
+	panics []rpc.Panic
+}
+
+// ReportPanic implementation.
+func (s *reportingServer) ReportPanic(ctx context.Context, p rpc.Panic) {
+	s.panics = append(s.panics, p)
+}
+
+// Test panic recovery.
+func TestIntercept_panic(t *testing.T) {
+	info := rpc.MethodInfo{Name: "add_item"}
+
+	handler := func(ctx context.Context, in interface{}) (interface{}, error) {
+		panic("boom")
+	}
+
+	t.Run("with a PanicReporter", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/add_item", nil)
+		r.Header.Set("X-Request-Id", "abc")
+		ctx := rpc.NewRequestContext(context.Background(), r)
+
+		s := &reportingServer{}
+		_, err := rpc.Intercept(ctx, s, info, nil, handler)
+		assert.EqualError(t, err, "panic: boom")
+
+		assert.Len(t, s.panics, 1)
+		p := s.panics[0]
+		assert.Equal(t, "boom", p.Value)
+		assert.Equal(t, "add_item", p.Method)
+		assert.Equal(t, "abc", p.RequestID)
+		assert.Contains(t, string(p.Stack), "recover_test.go")
+
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, err)
+		assert.Equal(t, 500, w.Code)
+		assert.Equal(t, "{\"type\":\"internal\",\"message\":\"Internal server error\"}", strings.TrimSpace(w.Body.String()))
+	})
+
+	t.Run("with a panicking interceptor", func(t *testing.T) {
+		s := &reportingServer{}
+		s.interceptors = []rpc.Interceptor{
+			func(ctx context.Context, info rpc.MethodInfo, in interface{}, next rpc.Handler) (interface{}, error) {
+				panic("interceptor")
+			},
+		}
+
+		_, err := rpc.Intercept(context.Background(), s, info, nil, handler)
+		assert.EqualError(t, err, "panic: interceptor")
+		assert.Len(t, s.panics, 1)
+	})
+
+	t.Run("with an interceptor observing the panic", func(t *testing.T) {
+		var observed error
+		s := &reportingServer{}
+		s.interceptors = []rpc.Interceptor{
+			func(ctx context.Context, info rpc.MethodInfo, in interface{}, next rpc.Handler) (interface{}, error) {
+				res, err := next(ctx, in)
+				observed = err
+				return res, err
+			},
+		}
+
+		_, err := rpc.Intercept(context.Background(), s, info, nil, handler)
+		assert.EqualError(t, err, "panic: boom")
+		assert.EqualError(t, observed, "panic: boom")
+		assert.Len(t, s.panics, 1)
+	})
+
+	t.Run("without a PanicReporter", func(t *testing.T) {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		defer log.SetOutput(os.Stderr)
+
+		_, err := rpc.Intercept(context.Background(), struct{}{}, info, nil, handler)
+		assert.EqualError(t, err, "panic: boom")
+		assert.Contains(t, buf.String(), "rpc: panic: boom in method add_item")
+	})
+}
@@ -1,33 +1,216 @@
 package rpc
 
 import (
+	"bytes"
+	"io"
 	"net/http"
+	"sync"
 
 	jsoniter "github.com/json-iterator/go"
 )
 
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
 
-// ReadRequest parses application/json request bodies into value, or returns an error.
-func ReadRequest(r *http.Request, value interface{}) error {
-	switch r.Header.Get("Content-Type") {
-	case "application/json":
-		// decode
-		err := json.NewDecoder(r.Body).Decode(value)
+// ReadOption is a ReadRequest option.
+type ReadOption func(*readOptions)
+
+// readOptions are the options applied by ReadRequest.
+type readOptions struct {
+	limits Limits
+	strict bool
+}
+
+// newReadOptions returns the read options of the given options.
+func newReadOptions(options []ReadOption) readOptions {
+	// options are applied to a pointer, which is only allocated when needed
+	if len(options) == 0 {
+		return readOptions{}
+	}
+
+	o := new(readOptions)
+	for _, option := range options {
+		option(o)
+	}
+	return *o
+}
+
+// WithLimits overrides DefaultLimits, zero value fields are inherited
+// from the defaults.
+func WithLimits(l Limits) ReadOption {
+	return func(o *readOptions) {
+		o.limits = l
+	}
+}
+
+// ReadRequest parses request bodies into value using the codec registered
+// for the request Content-Type, decompressing them according to the
+// Content-Encoding, or returns an error. Bodies exceeding the limits
+// are rejected before they are decoded, and bodies with unknown fields
+// after they are decoded in Strict mode.
+func ReadRequest(r *http.Request, value interface{}, options ...ReadOption) error {
+	o := newReadOptions(options)
+	limits := o.limits.merge(DefaultLimits)
+
+	c, ok := LookupCodec(headerValue(r.Header, "Content-Type"))
+	if !ok {
+		return BadRequest("Unsupported request Content-Type, must be " + supportedTypes())
+	}
+
+	// size
+	if limits.MaxBytes > 0 && r.ContentLength > limits.MaxBytes {
+		return errTooLarge(limits)
+	}
+
+	// decompress
+	var body io.Reader = r.Body
+	z, err := decompress(r)
+	if err != nil {
+		return err
+	}
+	if z != nil {
+		defer z.Close()
+		body = z
+	}
+
+	// read
+	buf := getBodyBuffer()
+	defer putBodyBuffer(buf)
+	err = readBody(buf, body, r.ContentLength, limits)
+	if err != nil {
+		return err
+	}
+
+	return decode(r, c, buf.Bytes(), value, o, limits)
+}
+
+// ReadQuery parses the query-string parameters of r into value like
+// ReadRequest, converting them to the types of the fields of value, see
+// QueryBody. It is used for the inputs of safe methods called with GET.
+func ReadQuery(r *http.Request, value interface{}, options ...ReadOption) error {
+	o := newReadOptions(options)
+	limits := o.limits.merge(DefaultLimits)
+
+	b, err := QueryBody(r.URL.Query(), value)
+	if err != nil {
+		return err
+	}
+
+	return decode(r, JSON, b, value, o, limits)
+}
+
+// decode decodes the body b of r with c into value, checking its structure
+// against limits, and validates it.
+func decode(r *http.Request, c Codec, b []byte, value interface{}, o readOptions, limits Limits) error {
+	var err error
+
+	// structure
+	if l, ok := c.(LimitChecker); ok {
+		err := l.CheckLimits(b, limits)
 		if err != nil {
-			return BadRequest("Failed to parse malformed request body, must be a valid JSON object")
+			return err
 		}
+	}
 
-		// validate
-		if v, ok := value.(Validator); ok {
-			err := v.Validate()
-			if err != nil {
-				return Invalid(err.Error())
-			}
+	// decode
+	if u, ok := c.(Unmarshaler); ok {
+		err = u.Unmarshal(b, value)
+	} else {
+		err = c.Decode(bytes.NewReader(b), value)
+	}
+	if err != nil {
+		return decodeError(c, b, value)
+	}
+
+	// unknown fields
+	if strict := o.strict || Strict; strict || LogUnknownFields {
+		err := checkUnknownFields(r, c, b, value, strict)
+		if err != nil {
+			return err
+		}
+	}
+
+	// validate
+	if v, ok := value.(Validator); ok {
+		switch err := v.Validate().(type) {
+		case nil:
+		case ValidationErrors:
+			return err
+		case ValidationError:
+			return ValidationErrors{err}
+		default:
+			return Invalid(err.Error())
 		}
+	}
+
+	return nil
+}
+
+// Unmarshaler is the interface used for codecs decoding bodies in memory
+// more efficiently than from a reader.
+type Unmarshaler interface {
+	Unmarshal(b []byte, v interface{}) error
+}
+
+// headerValue returns the first value of the canonical header key of h,
+// without canonicalizing the key like Header.Get.
+func headerValue(h http.Header, key string) string {
+	if v := h[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// bodyBuffer is a buffer for reading request bodies, with the reader
+// limiting them, pooled together so that neither is allocated per request.
+type bodyBuffer struct {
+	bytes.Buffer
+	limited io.LimitedReader
+}
+
+// bodyBuffers is a pool of request body buffers.
+var bodyBuffers = sync.Pool{
+	New: func() interface{} {
+		return new(bodyBuffer)
+	},
+}
+
+// getBodyBuffer returns an empty request body buffer from the pool.
+func getBodyBuffer() *bodyBuffer {
+	buf := bodyBuffers.Get().(*bodyBuffer)
+	buf.Reset()
+	return buf
+}
+
+// putBodyBuffer returns buf to the pool, unless it has grown too large to retain.
+func putBodyBuffer(buf *bodyBuffer) {
+	buf.limited.R = nil
+	if buf.Cap() > 64<<10 {
+		return
+	}
+	bodyBuffers.Put(buf)
+}
 
-		return nil
-	default:
-		return BadRequest("Unsupported request Content-Type, must be application/json")
+// readBody reads r of the given length, or -1 when unknown, into buf,
+// returning an error when it exceeds l.MaxBytes.
+func readBody(buf *bodyBuffer, r io.Reader, length int64, l Limits) error {
+	if l.MaxBytes > 0 {
+		buf.limited = io.LimitedReader{R: r, N: l.MaxBytes + 1}
+		r = &buf.limited
 	}
+
+	// bytes.Buffer.ReadFrom reads into a minimum of 512 bytes of free space
+	if length > 0 && (l.MaxBytes <= 0 || length <= l.MaxBytes) {
+		buf.Grow(int(length) + bytes.MinRead)
+	}
+
+	_, err := buf.ReadFrom(r)
+	if err != nil {
+		return BadRequest("Failed to read request body")
+	}
+
+	if l.MaxBytes > 0 && int64(buf.Len()) > l.MaxBytes {
+		return errTooLarge(l)
+	}
+
+	return nil
 }
@@ -1,6 +1,7 @@
 package rpc_test
 
 import (
+	"bytes"
 	"net/http/httptest"
 	"strings"
 	"testing"
@@ -16,7 +17,7 @@ func TestReadRequest(t *testing.T) {
 		r := httptest.NewRequest("GET", "/", strings.NewReader(`{ "name": "Tobi" }`))
 		var in struct{ Name string }
 		err := rpc.ReadRequest(r, &in)
-		assert.EqualError(t, err, `Unsupported request Content-Type, must be application/json`)
+		assert.EqualError(t, err, `Unsupported request Content-Type, must be application/cbor, application/json or application/msgpack`)
 	})
 
 	t.Run("with malformed JSON", func(t *testing.T) {
@@ -24,7 +25,7 @@ func TestReadRequest(t *testing.T) {
 		r.Header.Set("Content-Type", "application/json")
 		var in struct{ Name string }
 		err := rpc.ReadRequest(r, &in)
-		assert.EqualError(t, err, `Failed to parse malformed request body, must be a valid JSON object`)
+		assert.EqualError(t, err, `Failed to parse malformed request body, unexpected end of JSON input at byte offset 15`)
 	})
 
 	t.Run("with JSON array", func(t *testing.T) {
@@ -43,6 +44,23 @@ func TestReadRequest(t *testing.T) {
 		assert.NoError(t, err, "parsing")
 		assert.Equal(t, "Tobi", in.Name)
 	})
+
+	t.Run("with a json body and charset", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", strings.NewReader(`{ "name": "Tobi" }`))
+		r.Header.Set("Content-Type", "application/json; charset=utf-8")
+		var in struct{ Name string }
+		err := rpc.ReadRequest(r, &in)
+		assert.NoError(t, err, "parsing")
+		assert.Equal(t, "Tobi", in.Name)
+	})
+
+	t.Run("with malformed MessagePack", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", strings.NewReader(`{ "name": "Tobi" }`))
+		r.Header.Set("Content-Type", "application/msgpack")
+		var in struct{ Name string }
+		err := rpc.ReadRequest(r, &in)
+		assert.EqualError(t, err, `Failed to parse malformed request body, must be a valid MessagePack object`)
+	})
 }
 
 // Benchmark requests.
@@ -59,3 +77,22 @@ func BenchmarkReadRequest(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkReadRequest_body(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(1)
+
+	body := []byte(`{ "name": "Tobi", "species": "ferret", "email": "tobi@apex.sh" }`)
+	br := bytes.NewReader(body)
+	r := httptest.NewRequest("POST", "/", br)
+	r.Header.Set("Content-Type", "application/json")
+
+	for i := 0; i < b.N; i++ {
+		br.Reset(body)
+		var in struct{ Name, Species, Email string }
+		err := rpc.ReadRequest(r, &in)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,19 +1,176 @@
 package rpc
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"sync"
 )
 
-// WriteResponse writes a JSON response, or 204 if the value is nil
-// to indicate there is no content.
+// Negotiate returns a ResponseWriter which encodes and compresses responses
+// and errors written with WriteResponse and WriteError using the codec and
+// compressor negotiated for r. The request id of r is echoed in the
+// X-Request-Id response header. Responses are pretty printed when the
+// query-string of r has the pretty parameter.
+func Negotiate(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	w.Header().Add("Vary", "Accept")
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.Header().Set("X-Request-Id", RequestID(r))
+	_, pretty := r.URL.Query()["pretty"]
+	return &responseWriter{
+		ResponseWriter: w,
+		codec:          NegotiateCodec(r),
+		compressor:     NegotiateCompressor(r),
+		pretty:         pretty,
+	}
+}
+
+// responseWriter is a response writer carrying the negotiated response encoding.
+type responseWriter struct {
+	http.ResponseWriter
+	codec      Codec
+	compressor Compressor
+	pretty     bool
+}
+
+// PrettyEncoder is the interface used for codecs which support pretty printing,
+// used for responses in Debug mode or requested with the pretty parameter.
+type PrettyEncoder interface {
+	EncodePretty(w io.Writer, v interface{}) error
+}
+
+// buffers is a pool of buffers used for encoding responses.
+var buffers = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	buf := buffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer returns buf to the pool, unless it has grown too large to retain.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > 64<<10 {
+		return
+	}
+	buffers.Put(buf)
+}
+
+// Flush implementation.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// WriteResponse writes a response encoded with the codec negotiated by
+// Negotiate, defaulting to JSON, or 204 if the value is nil to indicate
+// there is no content.
 func WriteResponse(w http.ResponseWriter, value interface{}) {
 	if value == nil {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	enc := json.NewEncoder(w)
-	enc.SetIndent("", "  ")
-	enc.Encode(value)
+	write(w, http.StatusOK, value)
+}
+
+// write encodes value with the negotiated codec and writes it with the
+// given status, compressing bodies of at least CompressionThreshold bytes
+// when a compressor was negotiated. Values which fail to encode are
+// replaced by a 500 internal error.
+func write(w http.ResponseWriter, status int, value interface{}) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	c, err := encode(w, buf, value)
+	if err != nil {
+		status, c = encodeError(w, buf, err)
+	}
+
+	writeBody(w, status, c.ContentType(), buf.Bytes())
+}
+
+// encode writes the encoding of value with the codec negotiated for w to buf,
+// pretty printed in Debug mode or when it was requested, returning the codec.
+func encode(w http.ResponseWriter, buf *bytes.Buffer, value interface{}) (Codec, error) {
+	c := negotiatedCodec(w)
+
+	pretty := Debug
+	if rw, ok := w.(*responseWriter); ok {
+		pretty = pretty || rw.pretty
+	}
+
+	if p, ok := c.(PrettyEncoder); ok && pretty {
+		return c, p.EncodePretty(buf, value)
+	}
+
+	return c, c.Encode(buf, value)
+}
+
+// encodeError replaces the contents of buf with the encoding of a 500 error
+// for a response which failed to encode with err, falling back to JSON when
+// the negotiated codec fails again, returning its status and codec.
+func encodeError(w http.ResponseWriter, buf *bytes.Buffer, err error) (int, Codec) {
+	status, body := errorResponse(fmt.Errorf("encoding response: %w", err), w.Header().Get("X-Request-Id"))
+
+	buf.Reset()
+	c, err := encode(w, buf, body)
+	if err != nil {
+		buf.Reset()
+		c = JSON
+		JSON.Encode(buf, body)
+	}
+
+	return status, c
+}
+
+// writeBody writes the encoded body b with the given status, content type and
+// length, compressing it when a compressor was negotiated.
+func writeBody(w http.ResponseWriter, status int, contentType string, b []byte) {
+	var z Compressor
+	if rw, ok := w.(*responseWriter); ok {
+		z = rw.compressor
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+
+	if z == nil || len(b) < CompressionThreshold {
+		h.Set("Content-Length", strconv.Itoa(len(b)))
+		w.WriteHeader(status)
+		w.Write(b)
+		return
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	zw, err := z.NewWriter(buf)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	zw.Write(b)
+	zw.Close()
+
+	h.Set("Content-Encoding", z.Encoding())
+	h.Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// negotiatedCodec returns the codec negotiated for w, defaulting to JSON.
+func negotiatedCodec(w http.ResponseWriter) Codec {
+	if rw, ok := w.(*responseWriter); ok {
+		return rw.codec
+	}
+	return JSON
 }
@@ -3,6 +3,7 @@ package rpc_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -45,7 +46,49 @@ func TestWriteResponse(t *testing.T) {
 			Name: "Tobi",
 		})
 		assert.Equal(t, 200, w.Code)
-		assert.Equal(t, "{\n  \"name\": \"Tobi\"\n}", strings.TrimSpace(w.Body.String()))
+		assert.Equal(t, "{\"name\":\"Tobi\"}", strings.TrimSpace(w.Body.String()))
+		assert.Equal(t, "16", w.Header().Get("Content-Length"))
+	})
+
+	t.Run("with a value which fails to encode", func(t *testing.T) {
+		for _, accept := range []string{"application/json", "application/msgpack", "application/cbor"} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/", nil)
+			r.Header.Set("Accept", accept)
+			rpc.WriteResponse(rpc.Negotiate(w, r), map[string]interface{}{"items": make(chan int)})
+			assert.Equal(t, 500, w.Code, accept)
+			assert.Equal(t, accept, w.Header().Get("Content-Type"))
+			assert.Equal(t, strconv.Itoa(w.Body.Len()), w.Header().Get("Content-Length"))
+
+			c, _ := rpc.LookupCodec(accept)
+			var body map[string]interface{}
+			assert.NoError(t, c.Decode(w.Body, &body), accept)
+			assert.Equal(t, "internal", body["type"])
+		}
+	})
+
+	t.Run("with error details which fail to encode", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, rpc.Error(400, "bad_request", "Nope", rpc.WithDetails(func() {})))
+		assert.Equal(t, 500, w.Code)
+		assert.JSONEq(t, `{"type":"internal","message":"Internal server error"}`, w.Body.String())
+	})
+
+	t.Run("with pretty printing requested", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/?pretty", nil)
+		rpc.WriteResponse(rpc.Negotiate(w, r), map[string]string{"name": "Tobi"})
+		assert.Equal(t, "{\n  \"name\": \"Tobi\"\n}\n", w.Body.String())
+		assert.Equal(t, "21", w.Header().Get("Content-Length"))
+	})
+
+	t.Run("with pretty printing in debug mode", func(t *testing.T) {
+		rpc.Debug = true
+		defer func() { rpc.Debug = false }()
+
+		w := httptest.NewRecorder()
+		rpc.WriteResponse(w, map[string]string{"name": "Tobi"})
+		assert.Equal(t, "{\n  \"name\": \"Tobi\"\n}\n", w.Body.String())
 	})
 }
 
@@ -65,3 +108,36 @@ func BenchmarkWriteResponse(b *testing.B) {
 		rpc.WriteResponse(w, out)
 	}
 }
+
+func BenchmarkWriteResponse_pretty(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(1)
+
+	out := struct{ Name, Species, Email string }{
+		Name:    "Tobi",
+		Species: "Ferret",
+		Email:   "tobi@ferret.com",
+	}
+
+	w := rpc.Negotiate(DiscardResponseWriter{}, httptest.NewRequest("POST", "/?pretty", nil))
+	for i := 0; i < b.N; i++ {
+		rpc.WriteResponse(w, out)
+	}
+}
+
+func BenchmarkWriteResponse_compressed(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(1)
+
+	out := struct{ Names []string }{Names: make([]string, 1000)}
+	for i := range out.Names {
+		out.Names[i] = "Tobi"
+	}
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := rpc.Negotiate(DiscardResponseWriter{}, r)
+	for i := 0; i < b.N; i++ {
+		rpc.WriteResponse(w, out)
+	}
+}
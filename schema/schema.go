@@ -81,12 +81,13 @@ type ItemsObject struct {
 
 // Schema model.
 type Schema struct {
-	Name        string          `json:"name"`
-	Version     string          `json:"version"`
-	Description string          `json:"description"`
-	Methods     []Method        `json:"methods"`
-	Groups      []Group         `json:"groups"`
-	Types       map[string]Type `json:"types"`
+	Name        string           `json:"name"`
+	Version     string           `json:"version"`
+	Description string           `json:"description"`
+	Methods     []Method         `json:"methods"`
+	Groups      []Group          `json:"groups"`
+	Types       map[string]Type  `json:"types"`
+	Errors      map[string]Error `json:"errors"`
 	Go          struct {
 		Tags []string `json:"tags"`
 	} `json:"go"`
@@ -94,13 +95,37 @@ type Schema struct {
 
 // Method model.
 type Method struct {
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	Private     bool            `json:"private"`
-	Group       string          `json:"group"`
-	Inputs      []Field         `json:"inputs"`
-	Outputs     []Field         `json:"outputs"`
-	Examples    []MethodExample `json:"examples"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	Private      bool            `json:"private"`
+	Group        string          `json:"group"`
+	Inputs       []Field         `json:"inputs"`
+	Outputs      []Field         `json:"outputs"`
+	Examples     []MethodExample `json:"examples"`
+	Limits       Limits          `json:"limits"`
+	RateLimit    RateLimit       `json:"rate_limit"`
+	Errors       []string        `json:"errors"`
+	Stream       bool            `json:"stream"`
+	Idempotent   bool            `json:"idempotent"`
+	Strict       bool            `json:"strict"`
+	Safe         bool            `json:"safe"`
+	CacheControl string          `json:"cache_control"`
+	Auth         string          `json:"auth"`
+	Scopes       []string        `json:"scopes"`
+}
+
+// Limits model.
+type Limits struct {
+	MaxBytes       int64 `json:"max_bytes"`
+	MaxDepth       int   `json:"max_depth"`
+	MaxArrayLength int   `json:"max_array_length"`
+}
+
+// RateLimit model.
+type RateLimit struct {
+	Rate        float64 `json:"rate"`
+	Burst       int     `json:"burst"`
+	Concurrency int     `json:"concurrency"`
 }
 
 // MethodExample model.
@@ -138,6 +163,14 @@ type Example struct {
 	Value       interface{} `json:"value"`
 }
 
+// Error model.
+type Error struct {
+	Name        string  `json:"name"`
+	Status      int     `json:"status"`
+	Description string  `json:"description"`
+	Details     []Field `json:"details"`
+}
+
 // Group model.
 type Group struct {
 	Name        string `json:"name"`
@@ -166,6 +199,19 @@ func (s Schema) TypesSlice() (v []Type) {
 	return
 }
 
+// ErrorsSlice returns a sorted slice of errors.
+func (s Schema) ErrorsSlice() (v []Error) {
+	for _, e := range s.Errors {
+		v = append(v, e)
+	}
+
+	sort.Slice(v, func(i, j int) bool {
+		return v[i].Name < v[j].Name
+	})
+
+	return
+}
+
 // Load returns a schema loaded and validated from path.
 func Load(path string) (*Schema, error) {
 	// TODO: bake into the binary with Go's native 'embed' stuff once it's available
@@ -205,6 +251,24 @@ func Load(path string) (*Schema, error) {
 		s.Types[k] = v
 	}
 
+	// populate error names & sort details
+	for k, v := range s.Errors {
+		v.Name = k
+		sort.Slice(v.Details, func(i, j int) bool {
+			return v.Details[i].Name < v.Details[j].Name
+		})
+		s.Errors[k] = v
+	}
+
+	// check method errors
+	for _, m := range s.Methods {
+		for _, name := range m.Errors {
+			if _, ok := s.Errors[name]; !ok {
+				return nil, fmt.Errorf("method %q references undefined error %q", m.Name, name)
+			}
+		}
+	}
+
 	// sort groups
 	sort.Slice(s.Groups, func(i, j int) bool {
 		a := s.Groups[i]
@@ -0,0 +1,518 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/apex/rpc/internal/format"
+	"github.com/apex/rpc/schema"
+)
+
+// Server serves the methods of a schema by invoking the methods of an
+// implementation with reflection, without generating code. Requests are
+// routed and errors written like the routers generated by rpc-go-server.
+//
+// The implementation's providers, such as Authenticator, HealthChecker
+// or InterceptorProvider, are used like those of generated servers.
+type Server struct {
+	// Batch enables the /_batch route.
+	Batch bool
+
+	// Metrics records the metrics of method calls when set, such as
+	// metrics.New(s.Methods()...), otherwise the recorder of an
+	// implementation providing one with MetricsProvider is used.
+	Metrics MetricsRecorder
+
+	impl    interface{}
+	schema  *schema.Schema
+	methods map[string]*serverMethod
+}
+
+// serverMethod is a method of the schema and its implementation.
+type serverMethod struct {
+	schema.Method
+	info    MethodInfo
+	options []ReadOption
+	limit   RateLimit
+	fn      reflect.Value
+	in      reflect.Type
+}
+
+// Types used in method signatures.
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	streamType  = reflect.TypeOf((*Stream)(nil))
+)
+
+// NewServer returns a new server of the methods in s, implemented by the
+// exported methods of impl named after them, as in AddItem for add_item.
+//
+// Methods take a context.Context, followed by their input struct when they
+// have inputs, and return their output struct, or a pointer to it, and an
+// error, or only an error when they have no outputs:
+//
+//	AddItem(ctx context.Context, in AddItemInput) error
+//	GetItems(ctx context.Context) (*GetItemsOutput, error)
+//
+// Streaming methods take a *Stream as their last argument and return an error.
+//
+// An error is returned when a method is not implemented, its signature does
+// not match, or its input or output struct lacks a field of the schema.
+func NewServer(impl interface{}, s *schema.Schema) (*Server, error) {
+	srv := &Server{
+		impl:    impl,
+		schema:  s,
+		methods: make(map[string]*serverMethod),
+	}
+
+	var errs []string
+	v := reflect.ValueOf(impl)
+	for _, m := range s.Methods {
+		sm, err := newServerMethod(v, m)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		srv.methods["/"+m.Name] = sm
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("rpc: %s", strings.Join(errs, ", "))
+	}
+
+	return srv, nil
+}
+
+// newServerMethod returns method m implemented by v, or an error when its signature does not match.
+func newServerMethod(v reflect.Value, m schema.Method) (*serverMethod, error) {
+	name := format.GoName(m.Name)
+
+	fn := v.MethodByName(name)
+	if !fn.IsValid() {
+		return nil, fmt.Errorf("method %s must be implemented by %s.%s", m.Name, v.Type(), name)
+	}
+	t := fn.Type()
+
+	// arguments
+	args := []reflect.Type{contextType}
+	var in reflect.Type
+	if len(m.Inputs) > 0 {
+		if t.NumIn() > 1 {
+			in = t.In(1)
+		}
+		args = append(args, in)
+	}
+	if m.Stream {
+		args = append(args, streamType)
+	}
+
+	// results
+	results := []reflect.Type{errorType}
+	if len(m.Outputs) > 0 && !m.Stream {
+		var out reflect.Type
+		if t.NumOut() > 1 {
+			out = t.Out(0)
+		}
+		results = []reflect.Type{out, errorType}
+	}
+
+	if !signature(t, args, results) {
+		return nil, fmt.Errorf("method %s must be implemented as %s", m.Name, signatureString(name, m))
+	}
+
+	if in != nil {
+		if err := checkFields(in, m.Inputs); err != nil {
+			return nil, fmt.Errorf("method %s input %s", m.Name, err)
+		}
+	}
+
+	if len(m.Outputs) > 0 && !m.Stream {
+		if err := checkFields(t.Out(0), m.Outputs); err != nil {
+			return nil, fmt.Errorf("method %s output %s", m.Name, err)
+		}
+	}
+
+	sm := &serverMethod{
+		Method: m,
+		fn:     fn,
+		in:     in,
+		info: MethodInfo{
+			Name:       m.Name,
+			Group:      m.Group,
+			Private:    m.Private,
+			Stream:     m.Stream,
+			Idempotent: m.Idempotent,
+			Scopes:     m.Scopes,
+		},
+		limit: RateLimit{
+			Rate:        m.RateLimit.Rate,
+			Burst:       m.RateLimit.Burst,
+			Concurrency: m.RateLimit.Concurrency,
+		},
+	}
+
+	if l := m.Limits; l.MaxBytes > 0 || l.MaxDepth > 0 || l.MaxArrayLength > 0 {
+		sm.options = append(sm.options, WithLimits(Limits{
+			MaxBytes:       l.MaxBytes,
+			MaxDepth:       l.MaxDepth,
+			MaxArrayLength: l.MaxArrayLength,
+		}))
+	}
+
+	if m.Strict {
+		sm.options = append(sm.options, WithStrict())
+	}
+
+	return sm, nil
+}
+
+// signature returns true if the function type t has the given arguments and results,
+// where nil matches a struct or a pointer to a struct.
+func signature(t reflect.Type, args, results []reflect.Type) bool {
+	if t.NumIn() != len(args) || t.NumOut() != len(results) || t.IsVariadic() {
+		return false
+	}
+
+	match := func(t, want reflect.Type) bool {
+		if want == nil {
+			return isStruct(t)
+		}
+		return t == want
+	}
+
+	for i, arg := range args {
+		if !match(t.In(i), arg) {
+			return false
+		}
+	}
+
+	for i, res := range results {
+		if !match(t.Out(i), res) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// signatureString returns the expected signature of method m implemented as name.
+func signatureString(name string, m schema.Method) string {
+	args := []string{"context.Context"}
+	if len(m.Inputs) > 0 {
+		args = append(args, name+"Input")
+	}
+	if m.Stream {
+		args = append(args, "*rpc.Stream")
+	}
+
+	res := "error"
+	if len(m.Outputs) > 0 && !m.Stream {
+		res = fmt.Sprintf("(*%sOutput, error)", name)
+	}
+
+	return fmt.Sprintf("func %s(%s) %s", name, strings.Join(args, ", "), res)
+}
+
+// isStruct returns true if t is a struct or a pointer to a struct.
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// checkFields returns an error if the struct or pointer to a struct t lacks
+// any of the fields.
+func checkFields(t reflect.Type, fields []schema.Field) error {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var missing []string
+	sf := structFields(t)
+	for _, f := range fields {
+		if _, ok := lookupField(sf, f.Name); !ok {
+			missing = append(missing, f.Name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%s must have fields %s", t, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// ServeHTTP implementation.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w = Negotiate(w, r)
+
+	if HandleCORS(w, r, s.impl) {
+		return
+	}
+
+	var cacheControl string
+
+	if r.Method == http.MethodGet {
+		switch m, ok := s.methods[r.URL.Path]; {
+		case r.URL.Path == "/_health":
+			WriteHealth(w, s.impl)
+			return
+		case r.URL.Path == "/_health/live":
+			WriteHealthReport(w, r, s.impl, Liveness, s.schema.Name, s.schema.Version)
+			return
+		case r.URL.Path == "/_health/ready":
+			WriteHealthReport(w, r, s.impl, Readiness, s.schema.Name, s.schema.Version)
+			return
+		case r.URL.Path == "/_metrics" && s.metrics() != nil:
+			s.metrics().ServeHTTP(w, r)
+			return
+		case ok && m.Safe && !m.Stream:
+			cacheControl = m.CacheControl
+		case ok, r.URL.Path == "/_batch" && s.Batch:
+			WriteError(w, MethodNotAllowed("Method not allowed", http.MethodPost, http.MethodOptions))
+			return
+		default:
+			WriteError(w, BadRequest("Invalid method"))
+			return
+		}
+	}
+
+	if r.Method == http.MethodPost || r.Method == http.MethodGet {
+		ctx, cancel := WithTimeout(r.Context(), r)
+		defer cancel()
+		r = r.WithContext(ctx)
+		ctx = NewRequestContext(ctx, r)
+		ctx = NewTraceContext(ctx, r)
+		ctx, err := s.authenticate(ctx, r)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		r = r.WithContext(ctx)
+
+		var res interface{}
+		m, ok := s.methods[r.URL.Path]
+		switch {
+		case r.URL.Path == "/_batch" && s.Batch:
+			ServeBatch(w, r, s.callBatched)
+			return
+		case ok && m.Stream:
+			s.serveStream(ctx, w, r, m)
+			return
+		case ok && m.Idempotent:
+			ServeIdempotent(w, r, s.impl, func() (interface{}, error) {
+				return s.callMethod(ctx, r.URL.Path, r)
+			}, m.options...)
+			return
+		default:
+			res, err = s.callMethod(ctx, r.URL.Path, r)
+		}
+
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			WriteCacheable(w, r, res, cacheControl)
+			return
+		}
+
+		WriteResponse(w, res)
+		return
+	}
+
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", "GET, POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	WriteError(w, MethodNotAllowed("Method not allowed", "GET", "POST", "OPTIONS"))
+}
+
+// authenticate returns ctx with the principal of r, unless the method does not require authentication.
+func (s *Server) authenticate(ctx context.Context, r *http.Request) (context.Context, error) {
+	if m, ok := s.methods[r.URL.Path]; ok && m.Auth == "none" {
+		return ctx, nil
+	}
+
+	// batched calls are authenticated individually
+	if r.URL.Path == "/_batch" && s.Batch {
+		return ctx, nil
+	}
+
+	return Authenticate(ctx, s.impl, r)
+}
+
+// callBatched invokes a batched call of the method at path, authenticated like a single call.
+func (s *Server) callBatched(ctx context.Context, path string, r *http.Request) (interface{}, error) {
+	ctx, err := s.authenticate(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return s.callMethod(ctx, path, r.WithContext(ctx))
+}
+
+// begin records the start of a call to the method at path, returning
+// a function recording its end.
+func (s *Server) begin(path string) func(err error) {
+	m := s.metrics()
+	if m == nil {
+		return func(error) {}
+	}
+	return m.Begin(path)
+}
+
+// metrics returns the Metrics recorder, or the one provided by the implementation.
+func (s *Server) metrics() MetricsRecorder {
+	if s.Metrics != nil {
+		return s.Metrics
+	}
+	return metricsRecorder(s.impl)
+}
+
+// callMethod invokes the method at path with the input read from r.
+func (s *Server) callMethod(ctx context.Context, path string, r *http.Request) (res interface{}, err error) {
+	done := s.begin(path)
+	defer func() {
+		done(err)
+	}()
+
+	m, ok := s.methods[path]
+	if !ok {
+		return nil, BadRequest("Invalid method")
+	}
+
+	// streams are routed by ServeHTTP
+	if m.Stream {
+		return nil, BadRequest("Streaming methods must not be batched")
+	}
+
+	release, err := s.prepare(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	in, err := s.readInput(r, m)
+	if err != nil {
+		return nil, err
+	}
+
+	return Intercept(ctx, s.impl, m.info, in, func(ctx context.Context, in interface{}) (interface{}, error) {
+		return m.call(ctx, in)
+	})
+}
+
+// serveStream serves a call to the streaming method m.
+func (s *Server) serveStream(ctx context.Context, w http.ResponseWriter, r *http.Request, m *serverMethod) {
+	var err error
+	done := s.begin(r.URL.Path)
+	defer func() {
+		done(err)
+	}()
+
+	release, err := s.prepare(ctx, m)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	defer release()
+
+	in, err := s.readInput(r, m)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	stream := NewStream(w, r)
+	_, err = Intercept(ctx, s.impl, m.info, in, func(ctx context.Context, in interface{}) (interface{}, error) {
+		return m.call(ctx, in, reflect.ValueOf(stream))
+	})
+	stream.Finish(err)
+}
+
+// prepare authorizes and throttles a call to method m, returning
+// a function which must be called when the call completes.
+func (s *Server) prepare(ctx context.Context, m *serverMethod) (func(), error) {
+	if len(m.Scopes) > 0 {
+		if err := Authorize(ctx, m.Scopes...); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.limit.Rate > 0 || m.limit.Concurrency > 0 {
+		return Throttle(ctx, s.impl, m.Name, m.limit)
+	}
+
+	return func() {}, nil
+}
+
+// readInput returns the input of method m read from r, or nil when it has no inputs.
+func (s *Server) readInput(r *http.Request, m *serverMethod) (interface{}, error) {
+	if m.in == nil {
+		return nil, nil
+	}
+
+	t := m.in
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// safe methods called with GET read the query-string
+	read := ReadRequest
+	if r.Method == http.MethodGet {
+		read = ReadQuery
+	}
+
+	in := reflect.New(t)
+	err := read(r, in.Interface(), m.options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.in.Kind() == reflect.Ptr {
+		return in.Interface(), nil
+	}
+
+	return in.Elem().Interface(), nil
+}
+
+// call invokes the implementation of m with in, when it has inputs,
+// followed by the extra arguments, returning its output and error.
+func (m *serverMethod) call(ctx context.Context, in interface{}, extra ...reflect.Value) (interface{}, error) {
+	args := []reflect.Value{reflect.ValueOf(ctx)}
+	if m.in != nil {
+		args = append(args, reflect.ValueOf(in))
+	}
+	args = append(args, extra...)
+
+	results := m.fn.Call(args)
+
+	err, _ := results[len(results)-1].Interface().(error)
+	if len(results) == 1 {
+		return nil, err
+	}
+
+	out := results[0]
+	if out.Kind() == reflect.Ptr && out.IsNil() {
+		return nil, err
+	}
+
+	return out.Interface(), err
+}
+
+// Methods returns the names of the methods served, such as for metrics.New.
+func (s *Server) Methods() []string {
+	var names []string
+	for _, m := range s.schema.Methods {
+		names = append(names, m.Name)
+	}
+	return names
+}
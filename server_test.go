@@ -0,0 +1,268 @@
+package rpc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+	"github.com/apex/rpc/schema"
+)
+
+// todoItem is a to-do item.
+type todoItem struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+// todoServer implements the methods of the todo example schema.
+type todoServer struct {
+	mu    sync.Mutex
+	items []todoItem
+}
+
+// AddItemInput is the input of add_item.
+type AddItemInput struct {
+	Item string `json:"item"`
+}
+
+// GetItemsOutput is the output of get_items.
+type GetItemsOutput struct {
+	Items []todoItem `json:"items"`
+}
+
+// RemoveItemInput is the input of remove_item.
+type RemoveItemInput struct {
+	ID int `json:"id"`
+}
+
+// RemoveItemOutput is the output of remove_item.
+type RemoveItemOutput struct {
+	Item *todoItem `json:"item"`
+}
+
+// WatchItemsOutput is the output of watch_items.
+type WatchItemsOutput struct {
+	Item todoItem `json:"item"`
+}
+
+// AddItem implementation.
+func (s *todoServer) AddItem(ctx context.Context, in AddItemInput) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = append(s.items, todoItem{ID: len(s.items) + 1, Text: in.Item})
+	return nil
+}
+
+// GetItems implementation.
+func (s *todoServer) GetItems(ctx context.Context) (*GetItemsOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &GetItemsOutput{Items: s.items}, nil
+}
+
+// RemoveItem implementation.
+func (s *todoServer) RemoveItem(ctx context.Context, in *RemoveItemInput) (RemoveItemOutput, error) {
+	return RemoveItemOutput{}, rpc.Error(404, "item_not_found", "Item not found")
+}
+
+// WatchItems implementation.
+func (s *todoServer) WatchItems(ctx context.Context, stream *rpc.Stream) error {
+	s.mu.Lock()
+	items := s.items
+	s.mu.Unlock()
+
+	for _, item := range items {
+		if err := stream.Send(WatchItemsOutput{Item: item}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Authenticate implementation.
+func (s *todoServer) Authenticate(ctx context.Context, r *http.Request) (*rpc.Principal, error) {
+	switch r.Header.Get("Authorization") {
+	case "Bearer secret":
+		return &rpc.Principal{Subject: "tobi", Scopes: []string{"items:write"}}, nil
+	case "Bearer guest":
+		return &rpc.Principal{Subject: "loki"}, nil
+	default:
+		return nil, rpc.Unauthorized("Invalid token")
+	}
+}
+
+// todoMetricsServer is a todoServer providing a metrics recorder.
+type todoMetricsServer struct {
+	*todoServer
+	metricsServer
+}
+
+// badServer implements methods of the todo example schema with mismatched signatures.
+type badServer struct{}
+
+// AddItem implementation.
+func (badServer) AddItem(ctx context.Context, in struct{ Text string }) error {
+	return nil
+}
+
+// GetItems implementation.
+func (badServer) GetItems(ctx context.Context) error {
+	return nil
+}
+
+// RemoveItem implementation.
+func (badServer) RemoveItem(in RemoveItemInput) (*RemoveItemOutput, error) {
+	return nil, nil
+}
+
+// Test reflection-based servers.
+func TestNewServer(t *testing.T) {
+	s, err := schema.Load("examples/todo/schema.json")
+	assert.NoError(t, err, "loading schema")
+
+	t.Run("with mismatched methods", func(t *testing.T) {
+		_, err := rpc.NewServer(badServer{}, s)
+		assert.EqualError(t, err, `rpc: method add_item input struct { Text string } must have fields item, `+
+			`method get_items must be implemented as func GetItems(context.Context) (*GetItemsOutput, error), `+
+			`method remove_item must be implemented as func RemoveItem(context.Context, RemoveItemInput) (*RemoveItemOutput, error), `+
+			`method watch_items must be implemented by rpc_test.badServer.WatchItems`)
+	})
+
+	srv, err := rpc.NewServer(&todoServer{}, s)
+	assert.NoError(t, err, "creating server")
+	srv.Batch = true
+
+	call := func(method, path, body string, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Authorization", "Bearer secret")
+		r.Header.Set("X-Request-Id", "123")
+		for i := 0; i < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("with a method without outputs", func(t *testing.T) {
+		w := call("POST", "/add_item", `{ "item": "Ferret food" }`)
+		assert.Equal(t, 204, w.Code)
+	})
+
+	t.Run("with a method with outputs", func(t *testing.T) {
+		w := call("POST", "/get_items", ``)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "{\"items\":[{\"id\":1,\"text\":\"Ferret food\"}]}\n", w.Body.String())
+	})
+
+	t.Run("with a safe method over GET", func(t *testing.T) {
+		w := call("GET", "/get_items", ``)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+
+		w = call("GET", "/get_items", ``, "If-None-Match", w.Header().Get("ETag"))
+		assert.Equal(t, 304, w.Code)
+	})
+
+	t.Run("with an unsafe method over GET", func(t *testing.T) {
+		for _, path := range []string{"/add_item?item=x", "/watch_items", "/_batch"} {
+			w := call("GET", path, ``)
+			assert.Equal(t, 405, w.Code, path)
+			assert.Equal(t, "POST, OPTIONS", w.Header().Get("Allow"))
+			assert.Equal(t, "{\"type\":\"method_not_allowed\",\"message\":\"Method not allowed\",\"request_id\":\"123\"}\n", w.Body.String())
+		}
+	})
+
+	t.Run("with an invalid method over GET", func(t *testing.T) {
+		w := call("GET", "/nope", ``)
+		assert.Equal(t, 400, w.Code)
+		assert.Equal(t, "{\"type\":\"bad_request\",\"message\":\"Invalid method\",\"request_id\":\"123\"}\n", w.Body.String())
+	})
+
+	t.Run("with invalid input", func(t *testing.T) {
+		w := call("POST", "/add_item", `{ "item": "Ferret food", "text": "" }`)
+		assert.Equal(t, 400, w.Code)
+		assert.Contains(t, w.Body.String(), `"type":"unknown_field"`)
+	})
+
+	t.Run("without authentication", func(t *testing.T) {
+		w := call("POST", "/add_item", `{ "item": "Ferret food" }`, "Authorization", "")
+		assert.Equal(t, 401, w.Code)
+	})
+
+	t.Run("without authentication of a public method", func(t *testing.T) {
+		w := call("POST", "/get_items", ``, "Authorization", "")
+		assert.Equal(t, 200, w.Code)
+	})
+
+	t.Run("with missing scopes", func(t *testing.T) {
+		w := call("POST", "/remove_item", `{ "id": 1 }`, "Authorization", "Bearer guest")
+		assert.Equal(t, 403, w.Code)
+	})
+
+	t.Run("with an error", func(t *testing.T) {
+		w := call("POST", "/remove_item", `{ "id": 1 }`)
+		assert.Equal(t, 404, w.Code)
+		assert.Equal(t, "{\"type\":\"item_not_found\",\"message\":\"Item not found\",\"request_id\":\"123\"}\n", w.Body.String())
+	})
+
+	t.Run("with a streaming method", func(t *testing.T) {
+		w := call("POST", "/watch_items", ``)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "{\"data\":{\"item\":{\"id\":1,\"text\":\"Ferret food\"}}}\n{\"end\":true}\n", w.Body.String())
+	})
+
+	t.Run("with a batch", func(t *testing.T) {
+		w := call("POST", "/_batch", `[{ "method": "get_items" }, { "method": "watch_items" }]`)
+		assert.Equal(t, 200, w.Code)
+		assert.Contains(t, w.Body.String(), `"Streaming methods must not be batched"`)
+	})
+
+	t.Run("with a batch without authentication", func(t *testing.T) {
+		w := call("POST", "/_batch", `[{ "method": "get_items" }, { "method": "add_item", "input": { "item": "Ferret food" } }]`, "Authorization", "")
+		assert.Equal(t, 200, w.Code)
+		assert.Contains(t, w.Body.String(), `{"status":200,"result":{"items":`)
+		assert.Contains(t, w.Body.String(), `{"status":401,"error":{"type":"unauthorized","message":"Invalid token","request_id":"123"}}`)
+	})
+
+	t.Run("with health checks", func(t *testing.T) {
+		w := call("GET", "/_health/live", ``)
+		assert.Equal(t, 200, w.Code)
+		assert.Contains(t, w.Body.String(), `"name":"todo","version":"1.0.0"`)
+	})
+
+	t.Run("with an invalid method", func(t *testing.T) {
+		w := call("POST", "/nope", `{}`)
+		assert.Equal(t, 400, w.Code)
+	})
+
+	t.Run("with an unsupported HTTP method", func(t *testing.T) {
+		w := call("PUT", "/add_item", `{}`)
+		assert.Equal(t, 405, w.Code)
+		assert.Equal(t, "GET, POST, OPTIONS", w.Header().Get("Allow"))
+	})
+
+	t.Run("with metrics provided by the implementation", func(t *testing.T) {
+		impl := todoMetricsServer{&todoServer{}, metricsServer{&recorder{}}}
+		srv, err := rpc.NewServer(impl, s)
+		assert.NoError(t, err, "creating server")
+
+		r := httptest.NewRequest("POST", "/add_item", strings.NewReader(`{ "item": "Ferret food" }`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Authorization", "Bearer secret")
+		srv.ServeHTTP(httptest.NewRecorder(), r)
+
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", "/_metrics", nil))
+		assert.Equal(t, "metrics", w.Body.String())
+		assert.Equal(t, []string{"/add_item"}, impl.recorder.calls)
+	})
+}
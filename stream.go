@@ -0,0 +1,149 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+)
+
+// Media types of streamed responses.
+const (
+	ndjsonType      = "application/x-ndjson"
+	eventStreamType = "text/event-stream"
+)
+
+// Stream is a streamed response of newline-delimited JSON, or Server-Sent
+// Events when the request prefers text/event-stream. Each frame is flushed
+// as it is sent, and Stream is not safe for concurrent use.
+//
+// NDJSON lines are objects with a "data" value, an "error" value in the
+// format written by WriteError, or an "end" value when the stream finishes
+// successfully. SSE frames are "message" events with data, "error" events,
+// and an "end" event. Clients treat a stream without a final error or end
+// frame as truncated.
+type Stream struct {
+	ctx     context.Context
+	w       http.ResponseWriter
+	sse     bool
+	started bool
+}
+
+// NewStream returns a new stream for r.
+func NewStream(w http.ResponseWriter, r *http.Request) *Stream {
+	return &Stream{
+		ctx: r.Context(),
+		w:   w,
+		sse: acceptsEventStream(r),
+	}
+}
+
+// Send writes v as a frame and flushes it. The context error is returned
+// when the client has gone away.
+func (s *Stream) Send(v interface{}) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if s.sse {
+		return s.write("data: ", b, "\n\n")
+	}
+
+	return s.write(`{"data":`, b, "}\n")
+}
+
+// Flush writes the response header if nothing has been sent, and flushes
+// any buffered data to the client.
+func (s *Stream) Flush() error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	s.start()
+	s.flush()
+	return nil
+}
+
+// Finish ends the stream. A non-nil err is written with WriteError when
+// nothing has been sent, otherwise as an error frame, and a nil err as
+// an end frame.
+func (s *Stream) Finish(err error) {
+	if err != nil && !s.started {
+		WriteError(s.w, err)
+		return
+	}
+
+	if err != nil {
+		_, body := errorResponse(err, s.w.Header().Get("X-Request-Id"))
+		b, _ := json.Marshal(body)
+		if s.sse {
+			s.write("event: error\ndata: ", b, "\n\n")
+		} else {
+			s.write(`{"error":`, b, "}\n")
+		}
+		return
+	}
+
+	if s.sse {
+		s.write("event: end\ndata: ", []byte("{}"), "\n\n")
+		return
+	}
+
+	s.write(`{"end":`, []byte("true"), "}\n")
+}
+
+// write writes and flushes a frame of b surrounded by prefix and suffix.
+func (s *Stream) write(prefix string, b []byte, suffix string) error {
+	s.start()
+
+	frame := make([]byte, 0, len(prefix)+len(b)+len(suffix))
+	frame = append(frame, prefix...)
+	frame = append(frame, b...)
+	frame = append(frame, suffix...)
+
+	if _, err := s.w.Write(frame); err != nil {
+		return err
+	}
+
+	s.flush()
+	return nil
+}
+
+// start writes the response header if it has not been written.
+func (s *Stream) start() {
+	if s.started {
+		return
+	}
+
+	s.started = true
+	if s.sse {
+		s.w.Header().Set("Content-Type", eventStreamType)
+	} else {
+		s.w.Header().Set("Content-Type", ndjsonType)
+	}
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.WriteHeader(http.StatusOK)
+}
+
+// flush flushes the response writer when supported.
+func (s *Stream) flush() {
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// acceptsEventStream returns true if r prefers Server-Sent Events over NDJSON.
+func acceptsEventStream(r *http.Request) bool {
+	for _, t := range acceptedTypes(r.Header.Get("Accept")) {
+		switch t {
+		case eventStreamType:
+			return true
+		case ndjsonType:
+			return false
+		}
+	}
+	return false
+}
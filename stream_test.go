@@ -0,0 +1,88 @@
+package rpc_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// Test streams.
+func TestStream(t *testing.T) {
+	t.Run("with ndjson", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		w := httptest.NewRecorder()
+		s := rpc.NewStream(w, r)
+		assert.NoError(t, s.Send(map[string]int{"n": 1}))
+		assert.NoError(t, s.Send(map[string]int{"n": 2}))
+		s.Finish(nil)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		assert.Equal(t, "{\"data\":{\"n\":1}}\n{\"data\":{\"n\":2}}\n{\"end\":true}\n", w.Body.String())
+		assert.True(t, w.Flushed)
+	})
+
+	t.Run("with server-sent events", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept", "text/event-stream")
+		w := httptest.NewRecorder()
+		s := rpc.NewStream(w, r)
+		assert.NoError(t, s.Send(map[string]int{"n": 1}))
+		s.Finish(nil)
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.Equal(t, "data: {\"n\":1}\n\nevent: end\ndata: {}\n\n", w.Body.String())
+	})
+
+	t.Run("with nothing sent", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		w := httptest.NewRecorder()
+		s := rpc.NewStream(w, r)
+		s.Finish(nil)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		assert.Equal(t, "{\"end\":true}\n", w.Body.String())
+	})
+
+	t.Run("with an error before sending", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		w := httptest.NewRecorder()
+		s := rpc.NewStream(w, r)
+		s.Finish(rpc.BadRequest("Nope"))
+		assert.Equal(t, 400, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"type":"bad_request","message":"Nope"}`, w.Body.String())
+	})
+
+	t.Run("with an error after sending", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		w := httptest.NewRecorder()
+		s := rpc.NewStream(w, r)
+		assert.NoError(t, s.Send(1))
+		s.Finish(rpc.BadRequest("Nope"))
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "{\"data\":1}\n{\"error\":{\"type\":\"bad_request\",\"message\":\"Nope\"}}\n", w.Body.String())
+	})
+
+	t.Run("with an error event", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept", "text/event-stream")
+		w := httptest.NewRecorder()
+		s := rpc.NewStream(w, r)
+		assert.NoError(t, s.Send(1))
+		s.Finish(rpc.BadRequest("Nope"))
+		assert.Equal(t, "data: 1\n\nevent: error\ndata: {\"type\":\"bad_request\",\"message\":\"Nope\"}\n\n", w.Body.String())
+	})
+
+	t.Run("with a cancelled request", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		r := httptest.NewRequest("POST", "/", nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		s := rpc.NewStream(w, r)
+		cancel()
+		assert.Equal(t, context.Canceled, s.Send(1))
+		assert.Equal(t, context.Canceled, s.Flush())
+	})
+}
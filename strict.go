@@ -0,0 +1,189 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Strict enables rejecting unknown input fields for all methods, otherwise
+// only methods read with the WithStrict option reject them.
+var Strict = false
+
+// LogUnknownFields enables logging the unknown input fields of requests which
+// are not strict, for tracking the use of deprecated or misspelled fields.
+var LogUnknownFields = false
+
+// WithStrict rejects request bodies with fields unknown to the value with a
+// 400 unknown_field error naming their paths.
+func WithStrict() ReadOption {
+	return func(o *readOptions) {
+		o.strict = true
+	}
+}
+
+// checkUnknownFields checks the body b decoded with c into value for unknown
+// fields, returning an error when strict, otherwise logging them.
+func checkUnknownFields(r *http.Request, c Codec, b []byte, value interface{}, strict bool) error {
+	var v interface{}
+
+	var err error
+	if u, ok := c.(Unmarshaler); ok {
+		err = u.Unmarshal(b, &v)
+	} else {
+		err = c.Decode(bytes.NewReader(b), &v)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	paths := unknownFields("", v, reflect.TypeOf(value))
+	if len(paths) == 0 {
+		return nil
+	}
+
+	sort.Strings(paths)
+
+	if !strict {
+		log.Printf("rpc: unknown fields %s in request %s to %s", strings.Join(paths, ", "), RequestID(r), r.URL.Path)
+		return nil
+	}
+
+	return errUnknownFields(paths)
+}
+
+// errUnknownFields returns an unknown_field error for the given paths.
+func errUnknownFields(paths []string) error {
+	msg := fmt.Sprintf("Unknown field %s", paths[0])
+	if len(paths) > 1 {
+		msg = fmt.Sprintf("Unknown fields %s", strings.Join(paths, ", "))
+	}
+
+	details := struct {
+		Paths []string `json:"paths"`
+	}{paths}
+
+	return Error(http.StatusBadRequest, "unknown_field", msg, WithDetails(details))
+}
+
+// Interfaces of types decoding themselves, which are not checked for unknown fields.
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*interface{ UnmarshalJSON([]byte) error })(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// unknownFields returns the JSON pointer paths of the object keys of the
+// decoded value v which are not fields of t, prefixed with path.
+func unknownFields(path string, v interface{}, t reflect.Type) (paths []string) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || reflect.PtrTo(t).Implements(jsonUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := structFields(t)
+		eachKey(v, func(key string, v interface{}) {
+			p := path + "/" + escapePointer(key)
+			f, ok := lookupField(fields, key)
+			if !ok {
+				paths = append(paths, p)
+				return
+			}
+			paths = append(paths, unknownFields(p, v, f.Type)...)
+		})
+	case reflect.Map:
+		eachKey(v, func(key string, v interface{}) {
+			paths = append(paths, unknownFields(path+"/"+escapePointer(key), v, t.Elem())...)
+		})
+	case reflect.Slice, reflect.Array:
+		items, _ := v.([]interface{})
+		for i, item := range items {
+			paths = append(paths, unknownFields(fmt.Sprintf("%s/%d", path, i), item, t.Elem())...)
+		}
+	}
+
+	return paths
+}
+
+// eachKey calls fn with the keys and values of the decoded object v.
+func eachKey(v interface{}, fn func(key string, v interface{})) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		for k, v := range m {
+			fn(k, v)
+		}
+	case map[interface{}]interface{}:
+		for k, v := range m {
+			fn(fmt.Sprint(k), v)
+		}
+	}
+}
+
+// structFields returns the fields of struct t by their encoded name,
+// including those of embedded structs.
+func structFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+
+		// embedded structs
+		if f.Anonymous && name == "" {
+			et := f.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				for k, v := range structFields(et) {
+					if _, ok := fields[k]; !ok {
+						fields[k] = v
+					}
+				}
+				continue
+			}
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		fields[name] = f
+	}
+
+	return fields
+}
+
+// lookupField returns the field for key, matched case-insensitively as it is decoded.
+func lookupField(fields map[string]reflect.StructField, key string) (reflect.StructField, bool) {
+	if f, ok := fields[key]; ok {
+		return f, true
+	}
+
+	for name, f := range fields {
+		if strings.EqualFold(name, key) {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
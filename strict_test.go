@@ -0,0 +1,91 @@
+package rpc_test
+
+import (
+	"bytes"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// strictInput is an input with nested fields.
+type strictInput struct {
+	Name  string `json:"name"`
+	Items []struct {
+		Text string `json:"text"`
+	} `json:"items"`
+	Tags      map[string]struct{ Color string } `json:"tags"`
+	CreatedAt time.Time                         `json:"created_at"`
+	Ignored   string                            `json:"-"`
+}
+
+// Test strict decoding.
+func TestReadRequest_strict(t *testing.T) {
+	read := func(body string, options ...rpc.ReadOption) error {
+		r := httptest.NewRequest("POST", "/add_item", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		var in strictInput
+		return rpc.ReadRequest(r, &in, options...)
+	}
+
+	t.Run("with known fields", func(t *testing.T) {
+		err := read(`{ "name": "Tobi", "Items": [{ "text": "a" }], "tags": { "pet": { "color": "brown" } }, "created_at": "2020-01-01T00:00:00Z" }`, rpc.WithStrict())
+		assert.NoError(t, err)
+	})
+
+	t.Run("with an unknown field", func(t *testing.T) {
+		err := read(`{ "name": "Tobi", "items": [{ "text": "a" }, { "txt": "b" }] }`, rpc.WithStrict())
+		assert.EqualError(t, err, `Unknown field /items/1/txt`)
+		assert.Equal(t, 400, err.(rpc.StatusProvider).StatusCode())
+		assert.Equal(t, "unknown_field", err.(rpc.TypeProvider).Type())
+
+		w := httptest.NewRecorder()
+		rpc.WriteError(w, err)
+		assert.Contains(t, w.Body.String(), `"details":{"paths":["/items/1/txt"]}`)
+	})
+
+	t.Run("with unknown fields", func(t *testing.T) {
+		err := read(`{ "nme": "Tobi", "tags": { "pet": { "colour": "brown" } }, "Ignored": "" }`, rpc.WithStrict())
+		assert.EqualError(t, err, `Unknown fields /Ignored, /nme, /tags/pet/colour`)
+	})
+
+	t.Run("with strict mode enabled globally", func(t *testing.T) {
+		rpc.Strict = true
+		defer func() { rpc.Strict = false }()
+		err := read(`{ "nme": "Tobi" }`)
+		assert.EqualError(t, err, `Unknown field /nme`)
+	})
+
+	t.Run("with a CBOR body", func(t *testing.T) {
+		b, _ := cbor.Marshal(map[string]interface{}{"name": "Tobi", "txt": "a"})
+		r := httptest.NewRequest("POST", "/add_item", bytes.NewReader(b))
+		r.Header.Set("Content-Type", "application/cbor")
+		var in strictInput
+		err := rpc.ReadRequest(r, &in, rpc.WithStrict())
+		assert.EqualError(t, err, `Unknown field /txt`)
+	})
+
+	t.Run("with lenient mode", func(t *testing.T) {
+		assert.NoError(t, read(`{ "nme": "Tobi" }`))
+	})
+
+	t.Run("with lenient mode logging", func(t *testing.T) {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		defer log.SetOutput(os.Stderr)
+
+		rpc.LogUnknownFields = true
+		defer func() { rpc.LogUnknownFields = false }()
+
+		assert.NoError(t, read(`{ "nme": "Tobi" }`))
+		assert.Contains(t, buf.String(), "rpc: unknown fields /nme in request ")
+		assert.Contains(t, buf.String(), " to /add_item")
+	})
+}
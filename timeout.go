@@ -0,0 +1,36 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MaxTimeout is the maximum timeout which clients may request.
+var MaxTimeout = 24 * time.Hour
+
+// WithTimeout returns a copy of ctx with the deadline requested by the
+// Rpc-Timeout header of r, in milliseconds, capped at MaxTimeout. Requests
+// without a valid header keep the deadline of ctx, if any.
+func WithTimeout(ctx context.Context, r *http.Request) (context.Context, context.CancelFunc) {
+	ms, err := strconv.ParseInt(r.Header.Get("Rpc-Timeout"), 10, 64)
+	if err != nil || ms < 0 {
+		return context.WithCancel(ctx)
+	}
+
+	// cap before converting, as large values overflow the duration
+	if max := int64(MaxTimeout / time.Millisecond); ms > max {
+		ms = max
+	}
+
+	return context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+}
+
+// deadline returns a deadline exceeded error when the deadline of ctx has passed.
+func deadline(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return DeadlineExceeded("Deadline exceeded")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package rpc_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// Test request timeouts.
+func TestWithTimeout(t *testing.T) {
+	t.Run("with a timeout", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Rpc-Timeout", "5000")
+		ctx, cancel := rpc.WithTimeout(context.Background(), r)
+		defer cancel()
+		d, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(5*time.Second), d, time.Second)
+	})
+
+	t.Run("with a timeout above the maximum", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Rpc-Timeout", "9223372036854775807")
+		ctx, cancel := rpc.WithTimeout(context.Background(), r)
+		defer cancel()
+		assert.NoError(t, ctx.Err())
+		d, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(rpc.MaxTimeout), d, time.Second)
+	})
+
+	t.Run("without a timeout", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		ctx, cancel := rpc.WithTimeout(context.Background(), r)
+		defer cancel()
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+	})
+
+	t.Run("with an invalid timeout", func(t *testing.T) {
+		for _, v := range []string{"soon", "-1", "1.5"} {
+			r := httptest.NewRequest("POST", "/", nil)
+			r.Header.Set("Rpc-Timeout", v)
+			ctx, cancel := rpc.WithTimeout(context.Background(), r)
+			_, ok := ctx.Deadline()
+			assert.False(t, ok, v)
+			cancel()
+		}
+	})
+
+	t.Run("with an expired timeout", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Rpc-Timeout", "0")
+		ctx, cancel := rpc.WithTimeout(context.Background(), r)
+		defer cancel()
+		<-ctx.Done()
+		assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+	})
+}
@@ -0,0 +1,245 @@
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceContext is a W3C trace context.
+type TraceContext struct {
+	// TraceID is the id of the trace, 32 lowercase hex characters.
+	TraceID string
+
+	// SpanID is the id of the current span, 16 lowercase hex characters,
+	// which is the caller's span for incoming requests, or empty when the
+	// trace was started by the server.
+	SpanID string
+
+	// Sampled is true when the spans of the trace are recorded.
+	Sampled bool
+}
+
+// String returns the traceparent header value of the trace context, or an
+// empty string when it has no span.
+func (t TraceContext) String() string {
+	if t.SpanID == "" {
+		return ""
+	}
+
+	flags := "00"
+	if t.Sampled {
+		flags = "01"
+	}
+
+	return fmt.Sprintf("00-%s-%s-%s", t.TraceID, t.SpanID, flags)
+}
+
+// ParseTraceparent parses the traceparent header value v.
+func ParseTraceparent(v string) (TraceContext, bool) {
+	parts := strings.Split(v, "-")
+	if len(parts) < 4 {
+		return TraceContext{}, false
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+
+	// future versions may append fields
+	if !hexID(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return TraceContext{}, false
+	}
+
+	if !hexID(traceID, 32) || !hexID(spanID, 16) || !hexID(flags, 2) {
+		return TraceContext{}, false
+	}
+
+	b, _ := hex.DecodeString(flags)
+	return TraceContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: b[0]&1 == 1,
+	}, true
+}
+
+// hexID returns true if s is n lowercase hex characters, and not all zeros.
+func hexID(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+
+	zero := true
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+		zero = zero && c == '0'
+	}
+
+	return n == 2 || !zero
+}
+
+// traceKey is a private context key for the trace context.
+type traceKey struct{}
+
+// NewTraceContext returns a new context with the trace context of the
+// traceparent header of r, or a new sampled trace when it is missing or
+// invalid.
+func NewTraceContext(ctx context.Context, r *http.Request) context.Context {
+	t, ok := ParseTraceparent(r.Header.Get("traceparent"))
+	if !ok {
+		t = TraceContext{
+			TraceID: randomID(16),
+			Sampled: true,
+		}
+	}
+
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// TraceFromContext returns the trace context from context.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	t, ok := ctx.Value(traceKey{}).(TraceContext)
+	return t, ok
+}
+
+// TraceparentFromContext returns the traceparent header value for calls
+// made within the current span of ctx, or an empty string.
+func TraceparentFromContext(ctx context.Context) string {
+	t, _ := TraceFromContext(ctx)
+	return t.String()
+}
+
+// Span is a completed span.
+type Span struct {
+	TraceID    string                 `json:"trace_id"`
+	SpanID     string                 `json:"span_id"`
+	ParentID   string                 `json:"parent_id,omitempty"`
+	Name       string                 `json:"name"`
+	Start      time.Time              `json:"start"`
+	End        time.Time              `json:"end"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// SpanExporter is the interface used for exporting sampled spans.
+type SpanExporter interface {
+	ExportSpan(ctx context.Context, s Span)
+}
+
+// SpanExporterProvider is the interface used for servers providing a span exporter.
+type SpanExporterProvider interface {
+	SpanExporter() SpanExporter
+}
+
+// DefaultSpanExporter is the exporter used by servers which do not provide
+// one, spans are not exported when it is nil.
+var DefaultSpanExporter SpanExporter
+
+// startSpan starts the span of a call to the method of info, returning its
+// context and a function ending it with the error of the call.
+func startSpan(ctx context.Context, s interface{}, info MethodInfo) (context.Context, func(error)) {
+	parent, ok := TraceFromContext(ctx)
+	if !ok {
+		parent = TraceContext{TraceID: randomID(16), Sampled: true}
+	}
+
+	t := TraceContext{
+		TraceID: parent.TraceID,
+		SpanID:  randomID(8),
+		Sampled: parent.Sampled,
+	}
+	ctx = context.WithValue(ctx, traceKey{}, t)
+
+	e := DefaultSpanExporter
+	if p, ok := s.(SpanExporterProvider); ok {
+		e = p.SpanExporter()
+	}
+
+	if e == nil || !t.Sampled {
+		return ctx, func(error) {}
+	}
+
+	span := Span{
+		TraceID:    t.TraceID,
+		SpanID:     t.SpanID,
+		ParentID:   parent.SpanID,
+		Name:       info.Name,
+		Start:      time.Now(),
+		Attributes: spanAttributes(ctx, info),
+	}
+
+	return ctx, func(err error) {
+		span.End = time.Now()
+		if err != nil {
+			_, body := errorResponse(err, "")
+			span.Error = body.Type
+		}
+		e.ExportSpan(ctx, span)
+	}
+}
+
+// spanAttributes returns the span attributes of a call to the method of info.
+func spanAttributes(ctx context.Context, info MethodInfo) map[string]interface{} {
+	attrs := map[string]interface{}{
+		"rpc.method": info.Name,
+	}
+
+	if info.Group != "" {
+		attrs["rpc.group"] = info.Group
+	}
+
+	if info.Stream {
+		attrs["rpc.stream"] = true
+	}
+
+	if info.Idempotent {
+		attrs["rpc.idempotent"] = true
+	}
+
+	if len(info.Scopes) > 0 {
+		attrs["rpc.scopes"] = info.Scopes
+	}
+
+	if id := RequestIDFromContext(ctx); id != "" {
+		attrs["rpc.request_id"] = id
+	}
+
+	return attrs
+}
+
+// JSONExporter is a span exporter writing spans as newline-delimited JSON.
+type JSONExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONExporter returns a new exporter writing spans to w, such as os.Stdout.
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w}
+}
+
+// ExportSpan implementation.
+func (e *JSONExporter) ExportSpan(ctx context.Context, s Span) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.w.Write(append(b, '\n'))
+}
+
+// randomID returns a new random id of n bytes, hex encoded.
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
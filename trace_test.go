@@ -0,0 +1,136 @@
+package rpc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// tracedServer is a server providing a span exporter.
+type tracedServer struct {
+	exporter rpc.SpanExporter
+}
+
+// SpanExporter implementation.
+func (s tracedServer) SpanExporter() rpc.SpanExporter {
+	return s.exporter
+}
+
+// Test parsing traceparent values.
+func TestParseTraceparent(t *testing.T) {
+	t.Run("with a valid value", func(t *testing.T) {
+		tc, ok := rpc.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		assert.True(t, ok)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+		assert.Equal(t, "00f067aa0ba902b7", tc.SpanID)
+		assert.True(t, tc.Sampled)
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", tc.String())
+	})
+
+	t.Run("with a value which is not sampled", func(t *testing.T) {
+		tc, ok := rpc.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+		assert.True(t, ok)
+		assert.False(t, tc.Sampled)
+	})
+
+	t.Run("with a future version", func(t *testing.T) {
+		_, ok := rpc.ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+		assert.True(t, ok)
+	})
+
+	cases := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	}
+
+	for _, v := range cases {
+		_, ok := rpc.ParseTraceparent(v)
+		assert.False(t, ok, v)
+	}
+}
+
+// Test trace contexts.
+func TestNewTraceContext(t *testing.T) {
+	t.Run("with a traceparent header", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		ctx := rpc.NewTraceContext(context.Background(), r)
+		tc, ok := rpc.TraceFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+		assert.Equal(t, "00f067aa0ba902b7", tc.SpanID)
+	})
+
+	t.Run("without a traceparent header", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/", nil)
+		ctx := rpc.NewTraceContext(context.Background(), r)
+		tc, ok := rpc.TraceFromContext(ctx)
+		assert.True(t, ok)
+		assert.Len(t, tc.TraceID, 32)
+		assert.Empty(t, tc.SpanID)
+		assert.True(t, tc.Sampled)
+		assert.Empty(t, rpc.TraceparentFromContext(ctx))
+	})
+}
+
+// Test method spans.
+func TestIntercept_spans(t *testing.T) {
+	info := rpc.MethodInfo{Name: "add_item", Group: "items", Scopes: []string{"items:write"}}
+
+	r := httptest.NewRequest("POST", "/add_item", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	t.Run("with a sampled trace", func(t *testing.T) {
+		var buf bytes.Buffer
+		s := tracedServer{rpc.NewJSONExporter(&buf)}
+
+		var traceparent string
+		handler := func(ctx context.Context, in interface{}) (interface{}, error) {
+			traceparent = rpc.TraceparentFromContext(ctx)
+			return nil, rpc.BadRequest("Invalid item")
+		}
+
+		ctx := rpc.NewTraceContext(context.Background(), r)
+		_, err := rpc.Intercept(ctx, s, info, nil, handler)
+		assert.Error(t, err)
+
+		var span rpc.Span
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &span))
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID)
+		assert.Equal(t, "00f067aa0ba902b7", span.ParentID)
+		assert.Equal(t, "add_item", span.Name)
+		assert.Equal(t, "bad_request", span.Error)
+		assert.Equal(t, "items", span.Attributes["rpc.group"])
+		assert.False(t, span.End.Before(span.Start))
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+span.SpanID+"-01", traceparent)
+	})
+
+	t.Run("with a trace which is not sampled", func(t *testing.T) {
+		var buf bytes.Buffer
+		s := tracedServer{rpc.NewJSONExporter(&buf)}
+
+		r := httptest.NewRequest("POST", "/add_item", nil)
+		r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+		handler := func(ctx context.Context, in interface{}) (interface{}, error) {
+			return nil, nil
+		}
+
+		ctx := rpc.NewTraceContext(context.Background(), r)
+		_, err := rpc.Intercept(ctx, s, info, nil, handler)
+		assert.NoError(t, err)
+		assert.Empty(t, buf.String())
+	})
+}
@@ -1,19 +1,112 @@
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
 
 // Validator is the interface used for validating input.
 type Validator interface {
 	Validate() error
 }
 
+// FieldsProvider is the interface used for providing field validation errors.
+type FieldsProvider interface {
+	Fields() []ValidationError
+}
+
 // ValidationError is a field validation error.
 type ValidationError struct {
+	Path    string `json:"path,omitempty"`
 	Field   string `json:"field"`
 	Message string `json:"message"`
 }
 
 // Error implementation.
 func (e ValidationError) Error() string {
+	if e.Path != "" && e.Path != "/"+escapePointer(e.Field) {
+		return fmt.Sprintf("%s %s", e.Path, e.Message)
+	}
 	return fmt.Sprintf("%s %s", e.Field, e.Message)
 }
+
+// ValidationErrors is a collection of field validation errors, which
+// implements StatusProvider, TypeProvider and FieldsProvider.
+type ValidationErrors []ValidationError
+
+// Add adds an error for field with the given message.
+func (e *ValidationErrors) Add(field, message string) {
+	*e = append(*e, ValidationError{
+		Path:    "/" + escapePointer(field),
+		Field:   field,
+		Message: message,
+	})
+}
+
+// Merge adds the errors of err, typically returned by the Validate() method
+// of a nested value, with their paths prefixed by the JSON pointer prefix.
+func (e *ValidationErrors) Merge(prefix string, err error) {
+	switch v := err.(type) {
+	case nil:
+	case ValidationErrors:
+		for _, f := range v {
+			*e = append(*e, f.prefix(prefix))
+		}
+	case ValidationError:
+		*e = append(*e, v.prefix(prefix))
+	default:
+		*e = append(*e, ValidationError{
+			Path:    prefix,
+			Field:   prefix[strings.LastIndex(prefix, "/")+1:],
+			Message: err.Error(),
+		})
+	}
+}
+
+// Err returns e, or nil when there are no errors.
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error implementation.
+func (e ValidationErrors) Error() string {
+	var msgs []string
+	for _, f := range e {
+		msgs = append(msgs, f.Error())
+	}
+	return strings.Join(msgs, ", ")
+}
+
+// StatusCode implementation.
+func (e ValidationErrors) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// Type implementation.
+func (e ValidationErrors) Type() string {
+	return "invalid"
+}
+
+// Fields implementation.
+func (e ValidationErrors) Fields() []ValidationError {
+	return e
+}
+
+// prefix returns the error with its path prefixed.
+func (e ValidationError) prefix(prefix string) ValidationError {
+	if e.Path == "" {
+		e.Path = "/" + escapePointer(e.Field)
+	}
+	e.Path = prefix + e.Path
+	return e
+}
+
+// escapePointer returns s escaped for use as a JSON pointer reference token.
+func escapePointer(s string) string {
+	s = strings.Replace(s, "~", "~0", -1)
+	return strings.Replace(s, "/", "~1", -1)
+}
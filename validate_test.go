@@ -0,0 +1,124 @@
+package rpc_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tj/assert"
+
+	"github.com/apex/rpc"
+)
+
+// item is a nested validated value.
+type item struct {
+	Text string `json:"text"`
+}
+
+// Validate implementation.
+func (i *item) Validate() error {
+	var errs rpc.ValidationErrors
+
+	if i.Text == "" {
+		errs.Add("text", "is required")
+	}
+
+	return errs.Err()
+}
+
+// itemsInput is a validated input with nested values.
+type itemsInput struct {
+	Name  string `json:"name"`
+	Items []item `json:"items"`
+}
+
+// Validate implementation.
+func (i *itemsInput) Validate() error {
+	var errs rpc.ValidationErrors
+
+	if i.Name == "" {
+		errs.Add("name", "is required")
+	}
+
+	for n, v := range i.Items {
+		errs.Merge(fmt.Sprintf("/items/%d", n), v.Validate())
+	}
+
+	return errs.Err()
+}
+
+// Test validation errors.
+func TestValidationErrors(t *testing.T) {
+	t.Run("with no errors", func(t *testing.T) {
+		var errs rpc.ValidationErrors
+		assert.NoError(t, errs.Err())
+	})
+
+	t.Run("with nested errors", func(t *testing.T) {
+		in := itemsInput{Items: []item{{"a"}, {}, {"c"}, {}}}
+		err := in.Validate()
+		assert.EqualError(t, err, `name is required, /items/1/text is required, /items/3/text is required`)
+		assert.Equal(t, []rpc.ValidationError{
+			{Path: "/name", Field: "name", Message: "is required"},
+			{Path: "/items/1/text", Field: "text", Message: "is required"},
+			{Path: "/items/3/text", Field: "text", Message: "is required"},
+		}, err.(rpc.ValidationErrors).Fields())
+	})
+
+	t.Run("with a nested regular error", func(t *testing.T) {
+		var errs rpc.ValidationErrors
+		errs.Merge("/items/0", errors.New("is invalid"))
+		assert.Equal(t, []rpc.ValidationError{
+			{Path: "/items/0", Field: "0", Message: "is invalid"},
+		}, errs.Fields())
+	})
+
+	t.Run("with a nested ValidationError", func(t *testing.T) {
+		var errs rpc.ValidationErrors
+		errs.Merge("/item", rpc.ValidationError{Field: "text", Message: "is required"})
+		assert.Equal(t, []rpc.ValidationError{
+			{Path: "/item/text", Field: "text", Message: "is required"},
+		}, errs.Fields())
+	})
+
+	t.Run("with a field requiring escaping", func(t *testing.T) {
+		var errs rpc.ValidationErrors
+		errs.Add("a/b~c", "is required")
+		assert.Equal(t, "/a~1b~0c", errs[0].Path)
+		assert.EqualError(t, errs, `a/b~c is required`)
+	})
+}
+
+// Test validation when reading requests.
+func TestReadRequest_validation(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{ "items": [{ "text": "a" }, {}] }`))
+	r.Header.Set("Content-Type", "application/json")
+	var in itemsInput
+	err := rpc.ReadRequest(r, &in)
+	assert.Len(t, err.(rpc.ValidationErrors), 2)
+
+	w := httptest.NewRecorder()
+	r = httptest.NewRequest("POST", "/?pretty", nil)
+	r.Header.Set("X-Request-Id", "123")
+	rpc.WriteError(rpc.Negotiate(w, r), err)
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, `{
+  "type": "invalid",
+  "message": "name is required, /items/1/text is required",
+  "fields": [
+    {
+      "path": "/name",
+      "field": "name",
+      "message": "is required"
+    },
+    {
+      "path": "/items/1/text",
+      "field": "text",
+      "message": "is required"
+    }
+  ],
+  "request_id": "123"
+}`, strings.TrimSpace(w.Body.String()))
+}